@@ -0,0 +1,132 @@
+package log
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Compressor compresses rotated log files.
+type Compressor interface {
+	// Suffix is appended to the name of compressed files, e.g. ".gz".
+	Suffix() string
+	Compress(dst io.Writer, src io.Reader) error
+}
+
+var compressors = struct {
+	sync.RWMutex
+	m map[string]Compressor
+}{
+	m: map[string]Compressor{
+		"gzip": gzipCompressor{},
+	},
+}
+
+// RegisterCompressor makes a Compressor available to RotationConfig under
+// name. "gzip" is always registered.
+func RegisterCompressor(name string, c Compressor) {
+	compressors.Lock()
+	defer compressors.Unlock()
+
+	compressors.m[name] = c
+}
+
+func lookupCompressor(name string) (Compressor, error) {
+	if name == "" {
+		name = "gzip"
+	}
+
+	compressors.RLock()
+	defer compressors.RUnlock()
+
+	c, ok := compressors.m[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown compression: %q", name)
+	}
+
+	return c, nil
+}
+
+// compressionSuffixes returns the suffixes of all registered compressors.
+func compressionSuffixes() []string {
+	compressors.RLock()
+	defer compressors.RUnlock()
+
+	suffixes := make([]string, 0, len(compressors.m))
+	for _, c := range compressors.m {
+		suffixes = append(suffixes, c.Suffix())
+	}
+
+	return suffixes
+}
+
+type gzipCompressor struct{}
+
+func (gzipCompressor) Suffix() string {
+	return ".gz"
+}
+
+func (gzipCompressor) Compress(dst io.Writer, src io.Reader) error {
+	gz := gzip.NewWriter(dst)
+
+	if _, err := io.Copy(gz, src); err != nil {
+		return err
+	}
+
+	return gz.Close()
+}
+
+// compressFile compresses src next to it and removes src once the
+// compressed file is complete. It returns the name of the compressed file.
+func compressFile(src string, c Compressor) (dst string, err error) {
+	dst = src + c.Suffix()
+
+	in, err := os.Open(src)
+	if err != nil {
+		return "", err
+	}
+	defer in.Close()
+
+	info, err := in.Stat()
+	if err != nil {
+		return "", err
+	}
+
+	out, err := ioutil.TempFile(filepath.Dir(src), filepath.Base(dst)+".tmp")
+	if err != nil {
+		return "", err
+	}
+
+	defer func() {
+		if err != nil {
+			_ = out.Close()
+			_ = os.Remove(out.Name())
+		}
+	}()
+
+	if err = c.Compress(out, in); err != nil {
+		return "", fmt.Errorf("compress %s: %v", src, err)
+	}
+
+	if err = out.Chmod(info.Mode()); err != nil {
+		return "", err
+	}
+
+	if err = out.Sync(); err != nil {
+		return "", err
+	}
+
+	if err = out.Close(); err != nil {
+		return "", err
+	}
+
+	if err = os.Rename(out.Name(), dst); err != nil {
+		return "", err
+	}
+
+	return dst, os.Remove(src)
+}
@@ -0,0 +1,121 @@
+package log
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+)
+
+type contextKey struct{}
+
+// NewContext returns a copy of ctx that carries l.
+func NewContext(ctx context.Context, l *Logger) context.Context {
+	return context.WithValue(ctx, contextKey{}, l)
+}
+
+// FromContext returns the logger carried by ctx, or the global logger if
+// there is none. Like the loggers returned by Named and With, the global
+// logger is then kept open when it is replaced.
+func FromContext(ctx context.Context) *Logger {
+	if l := loggerFromContext(ctx); l != nil {
+		return l
+	}
+
+	g := acquireGlobal()
+	defer g.release()
+
+	g.pin()
+
+	return g.Logger
+}
+
+func loggerFromContext(ctx context.Context) *Logger {
+	if ctx == nil {
+		return nil
+	}
+
+	l, _ := ctx.Value(contextKey{}).(*Logger)
+
+	return l
+}
+
+// ContextExtractor turns values of a context into key-value pairs that
+// are added to the entries logged with it, e.g. a request ID.
+type ContextExtractor func(ctx context.Context) (keysAndValues []interface{})
+
+type namedExtractor struct {
+	name    string
+	extract ContextExtractor
+}
+
+var contextExtractors = struct {
+	sync.Mutex
+	list atomic.Value // []namedExtractor
+}{}
+
+// RegisterContextExtractor makes the ctx-aware log methods, such as
+// InfoCtx, call e for every entry. Registering another extractor with the
+// same name replaces it, a nil e removes it. Extractors run in the order
+// they were first registered.
+func RegisterContextExtractor(name string, e ContextExtractor) {
+	contextExtractors.Lock()
+	defer contextExtractors.Unlock()
+
+	prev, _ := contextExtractors.list.Load().([]namedExtractor)
+	list := make([]namedExtractor, 0, len(prev)+1)
+	found := false
+
+	for _, ne := range prev {
+		if ne.name == name {
+			found = true
+
+			if e == nil {
+				continue
+			}
+
+			ne.extract = e
+		}
+
+		list = append(list, ne)
+	}
+
+	if !found && e != nil {
+		list = append(list, namedExtractor{name: name, extract: e})
+	}
+
+	contextExtractors.list.Store(list)
+}
+
+// contextKeysAndValues prepends the key-value pairs extracted from ctx to
+// keysAndValues.
+func contextKeysAndValues(ctx context.Context, keysAndValues []interface{}) []interface{} {
+	list, _ := contextExtractors.list.Load().([]namedExtractor)
+	if ctx == nil || len(list) == 0 {
+		return keysAndValues
+	}
+
+	var extracted []interface{}
+
+	for _, ne := range list {
+		extracted = append(extracted, ne.extract(ctx)...)
+	}
+
+	if len(extracted) == 0 {
+		return keysAndValues
+	}
+
+	return append(extracted, keysAndValues...)
+}
+
+// contextLogger returns the logger for the package-level ctx-aware
+// functions. The global logger is kept from being closed until release
+// is called.
+func contextLogger(ctx context.Context) (l *Logger, release func()) {
+	if l := loggerFromContext(ctx); l != nil {
+		return l, func() {}
+	}
+
+	g := acquireGlobal()
+
+	return g.Logger, g.release
+}
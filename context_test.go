@@ -0,0 +1,115 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type requestIDKey struct{}
+
+func TestFromContext(t *testing.T) {
+	l := New(WithLogToStdout(false))
+
+	assert.Same(t, l, FromContext(NewContext(context.Background(), l)))
+	assert.Same(t, loadGlobal(), FromContext(context.Background()))
+}
+
+func TestRegisterContextExtractor(t *testing.T) {
+	defer RegisterContextExtractor("request_id", nil)
+	defer RegisterContextExtractor("tenant", nil)
+
+	RegisterContextExtractor("request_id", func(ctx context.Context) []interface{} {
+		if id, ok := ctx.Value(requestIDKey{}).(string); ok {
+			return []interface{}{"request_id", id}
+		}
+
+		return nil
+	})
+	RegisterContextExtractor("tenant", func(context.Context) []interface{} {
+		return []interface{}{"tenant", "acme"}
+	})
+
+	var buf bytes.Buffer
+
+	l := New(WithLogToStdout(false), WithOutput(&buf), AddCaller())
+	ctx := context.WithValue(context.Background(), requestIDKey{}, "r-1")
+
+	l.InfoCtx(ctx, "request", "status", 200)
+	assert.Contains(t, buf.String(), `/context_test.go:`)
+	assert.Contains(t, buf.String(), `"msg":"request","request_id":"r-1","tenant":"acme","status":200`)
+
+	// replace one, keeping the order
+	RegisterContextExtractor("request_id", func(context.Context) []interface{} {
+		return []interface{}{"request_id", "replaced"}
+	})
+
+	buf.Reset()
+	l.WarnCtx(context.Background(), "replaced")
+	assert.Contains(t, buf.String(), `"request_id":"replaced","tenant":"acme"`)
+
+	RegisterContextExtractor("tenant", nil)
+
+	buf.Reset()
+	l.ErrorCtx(context.Background(), "removed")
+	assert.NotContains(t, buf.String(), "tenant")
+}
+
+func TestInfoCtx(t *testing.T) {
+	var global, scoped bytes.Buffer
+
+	restore := ReplaceGlobal(New(WithLogToStdout(false), WithOutput(&global)))
+	defer restore()
+
+	l := New(WithLogToStdout(false), WithOutput(&scoped), AddCaller())
+
+	InfoCtx(NewContext(context.Background(), l.With("user", "alice")), "scoped")
+	InfoCtx(context.Background(), "global")
+
+	assert.Contains(t, scoped.String(), `/context_test.go:`)
+	assert.Contains(t, scoped.String(), `"msg":"scoped","user":"alice"`)
+	assert.Contains(t, global.String(), `"msg":"global"`)
+}
+
+func TestFromContext_SetOptions(t *testing.T) {
+	defer ReplaceGlobal(New(WithLogToStdout(false)))()
+
+	file := filepath.Join(tempDir(t), "global.log")
+	SetOptions(WithLogFiles(file))
+
+	l := FromContext(context.Background())
+
+	SetOptions(AddCaller())
+
+	l.Info("entry")
+	assert.NoError(t, l.Sync())
+	assert.Contains(t, readFile(t, file), "entry")
+}
+
+func TestInfoCtx_Disabled(t *testing.T) {
+	var buf bytes.Buffer
+
+	defer RegisterContextExtractor("calls", nil)
+	defer ReplaceGlobal(New(WithLogToStdout(false), WithOutput(&buf)))()
+
+	calls := 0
+	RegisterContextExtractor("calls", func(context.Context) []interface{} {
+		calls++
+
+		return nil
+	})
+
+	l := New(WithLogToStdout(false), WithOutput(&buf), WithLevel(WarnLevel))
+
+	l.DebugCtx(context.Background(), "dropped")
+	l.InfoCtx(context.Background(), "dropped")
+	DebugCtx(context.Background(), "dropped")
+	assert.Zero(t, calls)
+
+	l.WarnCtx(context.Background(), "logged")
+	InfoCtx(context.Background(), "logged")
+	assert.Equal(t, 2, calls)
+}
@@ -0,0 +1,389 @@
+package log
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// maxPooledFields keeps events that grew unusually large out of the pool.
+const maxPooledFields = 256
+
+// Event is an entry built by chained calls, e.g.
+//
+//	l.InfoEvent().Str("user", u).Int("n", n).Err(err).Msg("done")
+//
+// Events of disabled levels are nil, every method of a nil Event is a
+// no-op. An Event must not be used after Msg, Msgf or Send.
+type Event struct {
+	ce       *zapcore.CheckedEntry
+	fields   []Field
+	children []*Event
+	arrays   []*Array
+
+	// global is the global logger the event was started on by the
+	// package-level functions.
+	global *globalLogger
+}
+
+var eventPool = sync.Pool{
+	New: func() interface{} {
+		return &Event{fields: make([]Field, 0, 16)}
+	},
+}
+
+func newEvent(ce *zapcore.CheckedEntry) *Event {
+	if ce == nil {
+		return nil
+	}
+
+	e := eventPool.Get().(*Event)
+	e.ce = ce
+
+	return e
+}
+
+func putEvent(e *Event) {
+	for _, c := range e.children {
+		putEvent(c)
+	}
+
+	for _, a := range e.arrays {
+		putArray(a)
+	}
+
+	if cap(e.fields) > maxPooledFields {
+		return
+	}
+
+	for i := range e.fields {
+		e.fields[i] = Field{}
+	}
+
+	for i := range e.children {
+		e.children[i] = nil
+	}
+
+	for i := range e.arrays {
+		e.arrays[i] = nil
+	}
+
+	e.ce = nil
+	e.global = nil
+	e.fields = e.fields[:0]
+	e.children = e.children[:0]
+	e.arrays = e.arrays[:0]
+
+	eventPool.Put(e)
+}
+
+// Dict returns an event to be added as a nested object by Event.Dict or
+// Array.Dict, it cannot be written by itself.
+func Dict() *Event {
+	return eventPool.Get().(*Event)
+}
+
+// Arr returns an array to be added by Event.Array.
+func Arr() *Array {
+	return arrayPool.Get().(*Array)
+}
+
+func (e *Event) Str(key, value string) *Event {
+	if e == nil {
+		return e
+	}
+
+	e.fields = append(e.fields, zap.String(key, value))
+
+	return e
+}
+
+func (e *Event) Strs(key string, values []string) *Event {
+	if e == nil {
+		return e
+	}
+
+	e.fields = append(e.fields, zap.Strings(key, values))
+
+	return e
+}
+
+func (e *Event) Int(key string, value int) *Event {
+	if e == nil {
+		return e
+	}
+
+	e.fields = append(e.fields, zap.Int(key, value))
+
+	return e
+}
+
+func (e *Event) Int64(key string, value int64) *Event {
+	if e == nil {
+		return e
+	}
+
+	e.fields = append(e.fields, zap.Int64(key, value))
+
+	return e
+}
+
+func (e *Event) Uint64(key string, value uint64) *Event {
+	if e == nil {
+		return e
+	}
+
+	e.fields = append(e.fields, zap.Uint64(key, value))
+
+	return e
+}
+
+func (e *Event) Float64(key string, value float64) *Event {
+	if e == nil {
+		return e
+	}
+
+	e.fields = append(e.fields, zap.Float64(key, value))
+
+	return e
+}
+
+func (e *Event) Bool(key string, value bool) *Event {
+	if e == nil {
+		return e
+	}
+
+	e.fields = append(e.fields, zap.Bool(key, value))
+
+	return e
+}
+
+func (e *Event) Dur(key string, value time.Duration) *Event {
+	if e == nil {
+		return e
+	}
+
+	e.fields = append(e.fields, zap.Duration(key, value))
+
+	return e
+}
+
+func (e *Event) Time(key string, value time.Time) *Event {
+	if e == nil {
+		return e
+	}
+
+	e.fields = append(e.fields, zap.Time(key, value))
+
+	return e
+}
+
+// Err adds err under the key "error", a nil err adds nothing.
+func (e *Event) Err(err error) *Event {
+	if e == nil {
+		return e
+	}
+
+	e.fields = append(e.fields, zap.Error(err))
+
+	return e
+}
+
+func (e *Event) Stringer(key string, value fmt.Stringer) *Event {
+	if e == nil {
+		return e
+	}
+
+	e.fields = append(e.fields, zap.Stringer(key, value))
+
+	return e
+}
+
+func (e *Event) Any(key string, value interface{}) *Event {
+	if e == nil {
+		return e
+	}
+
+	e.fields = append(e.fields, zap.Any(key, value))
+
+	return e
+}
+
+// Fields adds typed fields.
+func (e *Event) Fields(fields ...Field) *Event {
+	if e == nil {
+		return e
+	}
+
+	e.fields = append(e.fields, fields...)
+
+	return e
+}
+
+// Ctx adds the key-value pairs extracted from ctx, see
+// RegisterContextExtractor.
+func (e *Event) Ctx(ctx context.Context) *Event {
+	if e == nil {
+		return e
+	}
+
+	e.fields = append(e.fields, keysAndValuesFields(contextKeysAndValues(ctx, nil))...)
+
+	return e
+}
+
+// Dict adds the fields of dict, created by Dict, as a nested object.
+func (e *Event) Dict(key string, dict *Event) *Event {
+	if e == nil {
+		putEvent(dict)
+		return e
+	}
+
+	e.fields = append(e.fields, zap.Object(key, dict))
+	e.children = append(e.children, dict)
+
+	return e
+}
+
+// Array adds arr, created by Arr.
+func (e *Event) Array(key string, arr *Array) *Event {
+	if e == nil {
+		putArray(arr)
+		return e
+	}
+
+	e.fields = append(e.fields, zap.Array(key, arr))
+	e.arrays = append(e.arrays, arr)
+
+	return e
+}
+
+// MarshalLogObject encodes the fields of a Dict.
+func (e *Event) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	for _, f := range e.fields {
+		f.AddTo(enc)
+	}
+
+	return nil
+}
+
+// Msg writes the event with msg.
+func (e *Event) Msg(msg string) {
+	if e == nil {
+		return
+	}
+
+	if g := e.global; g != nil {
+		// released even if the entry panics
+		defer g.eventDone()
+	}
+
+	e.ce.Message = msg
+	e.ce.Write(e.fields...)
+
+	putEvent(e)
+}
+
+// Msgf writes the event with a formatted message.
+func (e *Event) Msgf(format string, args ...interface{}) {
+	if e == nil {
+		return
+	}
+
+	e.Msg(fmt.Sprintf(format, args...))
+}
+
+// Send writes the event without a message.
+func (e *Event) Send() {
+	e.Msg("")
+}
+
+// Array is a list of values for Event.Array.
+type Array struct {
+	items []Field
+}
+
+var arrayPool = sync.Pool{
+	New: func() interface{} {
+		return &Array{items: make([]Field, 0, 8)}
+	},
+}
+
+func putArray(a *Array) {
+	for _, item := range a.items {
+		if dict, ok := item.Interface.(*Event); ok && item.Type == zapcore.ObjectMarshalerType {
+			putEvent(dict)
+		}
+	}
+
+	if cap(a.items) > maxPooledFields {
+		return
+	}
+
+	for i := range a.items {
+		a.items[i] = Field{}
+	}
+
+	a.items = a.items[:0]
+
+	arrayPool.Put(a)
+}
+
+func (a *Array) Str(value string) *Array {
+	a.items = append(a.items, zap.String("", value))
+	return a
+}
+
+func (a *Array) Int(value int) *Array {
+	a.items = append(a.items, zap.Int("", value))
+	return a
+}
+
+func (a *Array) Float64(value float64) *Array {
+	a.items = append(a.items, zap.Float64("", value))
+	return a
+}
+
+func (a *Array) Bool(value bool) *Array {
+	a.items = append(a.items, zap.Bool("", value))
+	return a
+}
+
+func (a *Array) Err(err error) *Array {
+	a.items = append(a.items, zap.NamedError("", err))
+	return a
+}
+
+// Dict adds the fields of dict, created by Dict, as an object.
+func (a *Array) Dict(dict *Event) *Array {
+	a.items = append(a.items, zap.Object("", dict))
+	return a
+}
+
+func (a *Array) MarshalLogArray(enc zapcore.ArrayEncoder) error {
+	for _, item := range a.items {
+		switch item.Type {
+		case zapcore.StringType:
+			enc.AppendString(item.String)
+		case zapcore.Int64Type:
+			enc.AppendInt64(item.Integer)
+		case zapcore.Float64Type:
+			enc.AppendFloat64(math.Float64frombits(uint64(item.Integer)))
+		case zapcore.BoolType:
+			enc.AppendBool(item.Integer == 1)
+		case zapcore.ErrorType:
+			enc.AppendString(item.Interface.(error).Error())
+		case zapcore.ObjectMarshalerType:
+			if err := enc.AppendObject(item.Interface.(zapcore.ObjectMarshaler)); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,118 @@
+package log
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLogger_InfoEvent(t *testing.T) {
+	var buf bytes.Buffer
+
+	l := New(WithLogToStdout(false), WithOutput(&buf), AddCaller())
+
+	l.InfoEvent().
+		Str("user", "alice").
+		Int("n", 3).
+		Bool("ok", true).
+		Dur("took", time.Second).
+		Err(errors.New("boom")).
+		Dict("req", Dict().Str("method", "GET").Dict("headers", Dict().Str("accept", "*/*"))).
+		Array("items", Arr().Str("a").Int(1).Bool(false).Float64(1.5).Dict(Dict().Int("id", 7))).
+		Msg("done")
+
+	assert.Contains(t, buf.String(), `/event_test.go:`)
+	assert.Contains(t, buf.String(), `"msg":"done","user":"alice","n":3,"ok":true,"took":1,"error":"boom",`+
+		`"req":{"method":"GET","headers":{"accept":"*/*"}},"items":["a",1,false,1.5,{"id":7}]}`)
+
+	buf.Reset()
+	l.WarnEvent().Msgf("formatted %d", 1)
+	l.ErrorEvent().Str("k", "v").Send()
+	assert.Contains(t, buf.String(), `"level":"warn"`)
+	assert.Contains(t, buf.String(), `"msg":"formatted 1"`)
+	assert.Contains(t, buf.String(), `"msg":"","k":"v"`)
+}
+
+func TestLogger_EventDisabled(t *testing.T) {
+	var buf bytes.Buffer
+
+	l := New(WithLogToStdout(false), WithOutput(&buf))
+
+	assert.Nil(t, l.DebugEvent())
+
+	l.DebugEvent().Str("k", "v").Dict("d", Dict().Int("i", 1)).Array("a", Arr().Str("s")).Msg("dropped")
+	assert.Empty(t, buf.String())
+
+	allocs := testing.AllocsPerRun(100, func() {
+		l.DebugEvent().Str("k", "v").Int("i", 1).Err(errDisabled).Msg("dropped")
+	})
+	assert.Zero(t, allocs)
+}
+
+func TestInfoEvent(t *testing.T) {
+	var buf bytes.Buffer
+
+	restore := ReplaceGlobal(New(WithLogToStdout(false), WithOutput(&buf), AddCaller()))
+	defer restore()
+
+	InfoEvent().Str("k", "v").Msg("global")
+	DebugEvent().Msg("dropped")
+
+	assert.Contains(t, buf.String(), `/event_test.go:`)
+	assert.Contains(t, buf.String(), `"msg":"global","k":"v"`)
+	assert.NotContains(t, buf.String(), "dropped")
+}
+
+func TestInfoEvent_SetOptions(t *testing.T) {
+	defer ReplaceGlobal(New(WithLogToStdout(false)))()
+
+	file := filepath.Join(tempDir(t), "global.log")
+	SetOptions(WithLogFiles(file))
+
+	prev := loadGlobal()
+	e := InfoEvent().Str("k", "v")
+
+	SetOptions(AddCaller())
+	assert.False(t, prev.writers.closed)
+
+	e.Msg("in flight")
+	assert.NoError(t, Sync())
+
+	assert.Contains(t, readFile(t, file), `"msg":"in flight","k":"v"`)
+
+	// closed once its last event is written
+	assert.True(t, prev.writers.closed)
+
+	// not kept open by events written before it was replaced
+	prev = loadGlobal()
+	InfoEvent().Msg("written")
+	SetOptions(AddCaller())
+	assert.True(t, prev.writers.closed)
+}
+
+func BenchmarkLogger_DebugEventDisabled(b *testing.B) {
+	l := New(WithLogToStdout(false), WithOutput(ioutil.Discard))
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		l.DebugEvent().Str("s", "v").Int("i", i).Err(errDisabled).Msg("disabled")
+	}
+}
+
+func BenchmarkLogger_InfoEvent(b *testing.B) {
+	l := New(WithLogToStdout(false), WithOutput(ioutil.Discard))
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		l.InfoEvent().Str("s", "v").Int("i", i).Err(errDisabled).Msg("enabled")
+	}
+}
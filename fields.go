@@ -0,0 +1,86 @@
+package log
+
+import (
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Field is a typed key-value pair for the *Fields methods, such as
+// InfoFields. Fields may also be passed to the sugared *w methods.
+type Field = zap.Field
+
+func Any(key string, value interface{}) Field {
+	return zap.Any(key, value)
+}
+
+func Bool(key string, value bool) Field {
+	return zap.Bool(key, value)
+}
+
+func Duration(key string, value time.Duration) Field {
+	return zap.Duration(key, value)
+}
+
+// Err adds err under the key "error", a nil err adds nothing.
+func Err(err error) Field {
+	return zap.Error(err)
+}
+
+func NamedErr(key string, err error) Field {
+	return zap.NamedError(key, err)
+}
+
+func Float64(key string, value float64) Field {
+	return zap.Float64(key, value)
+}
+
+func Int(key string, value int) Field {
+	return zap.Int(key, value)
+}
+
+func Int64(key string, value int64) Field {
+	return zap.Int64(key, value)
+}
+
+func Uint(key string, value uint) Field {
+	return zap.Uint(key, value)
+}
+
+func Uint64(key string, value uint64) Field {
+	return zap.Uint64(key, value)
+}
+
+func String(key string, value string) Field {
+	return zap.String(key, value)
+}
+
+func Strings(key string, values []string) Field {
+	return zap.Strings(key, values)
+}
+
+func Stringer(key string, value fmt.Stringer) Field {
+	return zap.Stringer(key, value)
+}
+
+func Time(key string, value time.Time) Field {
+	return zap.Time(key, value)
+}
+
+// Object adds value as a nested object.
+func Object(key string, value zapcore.ObjectMarshaler) Field {
+	return zap.Object(key, value)
+}
+
+// Namespace nests the fields after it below key.
+func Namespace(key string) Field {
+	return zap.Namespace(key)
+}
+
+// copyFields copies fields before they are handed to the cores, which keeps
+// the variadic fields of the callers on the stack when nothing is logged.
+func copyFields(fields []Field) []Field {
+	return append([]Field(nil), fields...)
+}
@@ -0,0 +1,118 @@
+package log
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLogger_InfoFields(t *testing.T) {
+	var buf bytes.Buffer
+
+	l := New(WithLogToStdout(false), WithOutput(&buf), AddCaller())
+
+	l.InfoFields("typed",
+		String("s", "v"),
+		Int("i", 1),
+		Bool("b", true),
+		Duration("d", time.Second),
+		Err(errors.New("boom")),
+		Err(nil),
+		Namespace("ns"),
+		Strings("ss", []string{"a"}),
+	)
+
+	assert.Contains(t, buf.String(), `/fields_test.go:`)
+	assert.Contains(t, buf.String(), `"msg":"typed","s":"v","i":1,"b":true,"d":1,"error":"boom","ns":{"ss":["a"]}}`)
+
+	buf.Reset()
+	l.DebugFields("dropped")
+	l.Infow("sugared", Int("i", 2))
+	assert.NotContains(t, buf.String(), "dropped")
+	assert.Contains(t, buf.String(), `"msg":"sugared","i":2`)
+}
+
+func TestLogger_Zap(t *testing.T) {
+	var buf bytes.Buffer
+
+	l := New(WithLogToStdout(false), WithOutput(&buf), AddCaller()).Named("db")
+
+	l.Zap().Info("zap", Int("i", 1))
+	l.Desugar().Debug("dropped")
+
+	assert.Contains(t, buf.String(), `"logger":"db"`)
+	assert.Contains(t, buf.String(), `/fields_test.go:`)
+	assert.Contains(t, buf.String(), `"msg":"zap","i":1`)
+	assert.NotContains(t, buf.String(), "dropped")
+}
+
+func TestInfoFields(t *testing.T) {
+	var buf bytes.Buffer
+
+	restore := ReplaceGlobal(New(WithLogToStdout(false), WithOutput(&buf), AddCaller()))
+	defer restore()
+
+	InfoFields("global", String("k", "v"))
+	assert.Contains(t, buf.String(), `/fields_test.go:`)
+	assert.Contains(t, buf.String(), `"msg":"global","k":"v"`)
+}
+
+func TestLogger_FieldsDisabledAllocs(t *testing.T) {
+	l := New(WithLogToStdout(false), WithOutput(ioutil.Discard))
+
+	allocs := testing.AllocsPerRun(100, func() {
+		l.DebugFields("disabled", String("s", "v"), Int("i", 1), Err(errDisabled))
+	})
+
+	assert.Zero(t, allocs)
+}
+
+var errDisabled = errors.New("disabled")
+
+func BenchmarkLogger_DebugFieldsDisabled(b *testing.B) {
+	l := New(WithLogToStdout(false), WithOutput(ioutil.Discard))
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		l.DebugFields("disabled", String("s", "v"), Int("i", i), Err(errDisabled))
+	}
+}
+
+func BenchmarkLogger_DebugwDisabled(b *testing.B) {
+	l := New(WithLogToStdout(false), WithOutput(ioutil.Discard))
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		l.Debugw("disabled", "s", "v", "i", i, "error", errDisabled)
+	}
+}
+
+func BenchmarkLogger_InfoFields(b *testing.B) {
+	l := New(WithLogToStdout(false), WithOutput(ioutil.Discard))
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		l.InfoFields("enabled", String("s", "v"), Int("i", i), Err(errDisabled))
+	}
+}
+
+func BenchmarkLogger_Infow(b *testing.B) {
+	l := New(WithLogToStdout(false), WithOutput(ioutil.Discard))
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		l.Infow("enabled", "s", "v", "i", i, "error", errDisabled)
+	}
+}
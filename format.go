@@ -3,6 +3,10 @@ package log
 import (
 	"fmt"
 	"strings"
+	"time"
+
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
 )
 
 type Format int
@@ -22,3 +26,186 @@ func ParseFormat(format string) (Format, error) {
 
 	return FormatConsole, fmt.Errorf("not a valid Format: %q", format)
 }
+
+// newEncoder returns an encoder in format. Entries without a time, which
+// only come from slog records, are encoded without the time key.
+func newEncoder(format Format, encoderCfg zapcore.EncoderConfig) zapcore.Encoder {
+	enc := newFormatEncoder(format, encoderCfg)
+	if encoderCfg.TimeKey == "" {
+		return enc
+	}
+
+	noTimeCfg := encoderCfg
+	noTimeCfg.TimeKey = ""
+
+	return &zeroTimeEncoder{
+		Encoder: enc,
+		noTime:  newFormatEncoder(format, noTimeCfg),
+	}
+}
+
+func newFormatEncoder(format Format, encoderCfg zapcore.EncoderConfig) zapcore.Encoder {
+	switch format {
+	case FormatJSON:
+		return zapcore.NewJSONEncoder(encoderCfg)
+	default:
+		return zapcore.NewConsoleEncoder(encoderCfg)
+	}
+}
+
+// zeroTimeEncoder encodes entries with a zero time through noTime, which
+// has the same fields but no time key.
+type zeroTimeEncoder struct {
+	zapcore.Encoder
+
+	noTime zapcore.Encoder
+}
+
+func (e *zeroTimeEncoder) Clone() zapcore.Encoder {
+	return &zeroTimeEncoder{
+		Encoder: e.Encoder.Clone(),
+		noTime:  e.noTime.Clone(),
+	}
+}
+
+func (e *zeroTimeEncoder) EncodeEntry(ent zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	if ent.Time.IsZero() {
+		return e.noTime.EncodeEntry(ent, fields)
+	}
+
+	return e.Encoder.EncodeEntry(ent, fields)
+}
+
+func (e *zeroTimeEncoder) AddArray(key string, arr zapcore.ArrayMarshaler) error {
+	if err := e.Encoder.AddArray(key, arr); err != nil {
+		return err
+	}
+
+	return e.noTime.AddArray(key, arr)
+}
+
+func (e *zeroTimeEncoder) AddObject(key string, obj zapcore.ObjectMarshaler) error {
+	if err := e.Encoder.AddObject(key, obj); err != nil {
+		return err
+	}
+
+	return e.noTime.AddObject(key, obj)
+}
+
+func (e *zeroTimeEncoder) AddReflected(key string, obj interface{}) error {
+	if err := e.Encoder.AddReflected(key, obj); err != nil {
+		return err
+	}
+
+	return e.noTime.AddReflected(key, obj)
+}
+
+func (e *zeroTimeEncoder) AddBinary(key string, v []byte) {
+	e.Encoder.AddBinary(key, v)
+	e.noTime.AddBinary(key, v)
+}
+
+func (e *zeroTimeEncoder) AddByteString(key string, v []byte) {
+	e.Encoder.AddByteString(key, v)
+	e.noTime.AddByteString(key, v)
+}
+
+func (e *zeroTimeEncoder) AddBool(key string, v bool) {
+	e.Encoder.AddBool(key, v)
+	e.noTime.AddBool(key, v)
+}
+
+func (e *zeroTimeEncoder) AddComplex128(key string, v complex128) {
+	e.Encoder.AddComplex128(key, v)
+	e.noTime.AddComplex128(key, v)
+}
+
+func (e *zeroTimeEncoder) AddComplex64(key string, v complex64) {
+	e.Encoder.AddComplex64(key, v)
+	e.noTime.AddComplex64(key, v)
+}
+
+func (e *zeroTimeEncoder) AddDuration(key string, v time.Duration) {
+	e.Encoder.AddDuration(key, v)
+	e.noTime.AddDuration(key, v)
+}
+
+func (e *zeroTimeEncoder) AddFloat64(key string, v float64) {
+	e.Encoder.AddFloat64(key, v)
+	e.noTime.AddFloat64(key, v)
+}
+
+func (e *zeroTimeEncoder) AddFloat32(key string, v float32) {
+	e.Encoder.AddFloat32(key, v)
+	e.noTime.AddFloat32(key, v)
+}
+
+func (e *zeroTimeEncoder) AddInt(key string, v int) {
+	e.Encoder.AddInt(key, v)
+	e.noTime.AddInt(key, v)
+}
+
+func (e *zeroTimeEncoder) AddInt64(key string, v int64) {
+	e.Encoder.AddInt64(key, v)
+	e.noTime.AddInt64(key, v)
+}
+
+func (e *zeroTimeEncoder) AddInt32(key string, v int32) {
+	e.Encoder.AddInt32(key, v)
+	e.noTime.AddInt32(key, v)
+}
+
+func (e *zeroTimeEncoder) AddInt16(key string, v int16) {
+	e.Encoder.AddInt16(key, v)
+	e.noTime.AddInt16(key, v)
+}
+
+func (e *zeroTimeEncoder) AddInt8(key string, v int8) {
+	e.Encoder.AddInt8(key, v)
+	e.noTime.AddInt8(key, v)
+}
+
+func (e *zeroTimeEncoder) AddString(key, v string) {
+	e.Encoder.AddString(key, v)
+	e.noTime.AddString(key, v)
+}
+
+func (e *zeroTimeEncoder) AddTime(key string, v time.Time) {
+	e.Encoder.AddTime(key, v)
+	e.noTime.AddTime(key, v)
+}
+
+func (e *zeroTimeEncoder) AddUint(key string, v uint) {
+	e.Encoder.AddUint(key, v)
+	e.noTime.AddUint(key, v)
+}
+
+func (e *zeroTimeEncoder) AddUint64(key string, v uint64) {
+	e.Encoder.AddUint64(key, v)
+	e.noTime.AddUint64(key, v)
+}
+
+func (e *zeroTimeEncoder) AddUint32(key string, v uint32) {
+	e.Encoder.AddUint32(key, v)
+	e.noTime.AddUint32(key, v)
+}
+
+func (e *zeroTimeEncoder) AddUint16(key string, v uint16) {
+	e.Encoder.AddUint16(key, v)
+	e.noTime.AddUint16(key, v)
+}
+
+func (e *zeroTimeEncoder) AddUint8(key string, v uint8) {
+	e.Encoder.AddUint8(key, v)
+	e.noTime.AddUint8(key, v)
+}
+
+func (e *zeroTimeEncoder) AddUintptr(key string, v uintptr) {
+	e.Encoder.AddUintptr(key, v)
+	e.noTime.AddUintptr(key, v)
+}
+
+func (e *zeroTimeEncoder) OpenNamespace(key string) {
+	e.Encoder.OpenNamespace(key)
+	e.noTime.OpenNamespace(key)
+}
@@ -1,123 +1,664 @@
 package log
 
-var global = New()
+import (
+	"context"
+	"sync"
+	"sync/atomic"
 
+	"go.uber.org/zap/zapcore"
+)
+
+// globalLogger tracks the package-level functions that are still writing
+// through a logger, so it can be flushed once it has been replaced.
+type globalLogger struct {
+	*Logger
+
+	// owned is set for loggers created by this package, they are closed
+	// when replaced unless pinned.
+	owned  bool
+	active sync.RWMutex
+
+	// pinned is set once loggers were derived from the package-level
+	// functions, they keep writing through it after it was replaced.
+	pinned int32
+
+	// events counts the package-level events not written yet. A retired
+	// logger that is due to be closed is closed by the last of them.
+	events  int32
+	closing int32
+}
+
+var (
+	global   atomic.Value // *globalLogger
+	globalMu sync.Mutex
+)
+
+func init() {
+	global.Store(&globalLogger{Logger: New(), owned: true})
+}
+
+func loadGlobal() *Logger {
+	return global.Load().(*globalLogger).Logger
+}
+
+func acquireGlobal() *globalLogger {
+	for {
+		g := global.Load().(*globalLogger)
+		g.active.RLock()
+
+		if global.Load() == g {
+			return g
+		}
+
+		// replaced in the meantime, the old one may already be closed
+		g.active.RUnlock()
+	}
+}
+
+func (g *globalLogger) release() {
+	g.active.RUnlock()
+}
+
+// pin keeps g open when it is replaced. It must be called between
+// acquireGlobal and release.
+func (g *globalLogger) pin() {
+	if atomic.LoadInt32(&g.pinned) == 0 {
+		atomic.StoreInt32(&g.pinned, 1)
+	}
+}
+
+// retire waits for the in-flight writes through g, then closes it if
+// closeOwned is set and it was created by this package and not pinned,
+// otherwise it is only flushed. With events not written yet, closing is
+// left to the last of them.
+func (g *globalLogger) retire(closeOwned bool) {
+	g.active.Lock()
+	defer g.active.Unlock()
+
+	if !closeOwned || !g.owned || atomic.LoadInt32(&g.pinned) != 0 {
+		_ = g.Sync()
+		return
+	}
+
+	atomic.StoreInt32(&g.closing, 1)
+
+	if atomic.LoadInt32(&g.events) == 0 {
+		_ = g.Close()
+	} else {
+		_ = g.Sync()
+	}
+}
+
+// eventDone is called once an event of g has been written.
+func (g *globalLogger) eventDone() {
+	if atomic.AddInt32(&g.events, -1) == 0 && atomic.LoadInt32(&g.closing) != 0 {
+		_ = g.Close()
+	}
+}
+
+func swapGlobal(fn func(prev *Logger) *Logger, owned, closePrev bool) *globalLogger {
+	globalMu.Lock()
+	prev := global.Load().(*globalLogger)
+	global.Store(&globalLogger{Logger: fn(prev.Logger), owned: owned})
+	globalMu.Unlock()
+
+	prev.retire(closePrev)
+
+	return prev
+}
+
+// globalCore writes through the global logger at the time of each entry,
+// for the outputs that take a nil logger. Callers are reported if the
+// global logger adds them.
+type globalCore struct {
+	fields []zapcore.Field
+}
+
+func (c *globalCore) Enabled(lvl zapcore.Level) bool {
+	g := acquireGlobal()
+	defer g.release()
+
+	return g.zap.Core().Enabled(lvl)
+}
+
+func (c *globalCore) With(fields []zapcore.Field) zapcore.Core {
+	return &globalCore{
+		fields: append(c.fields[:len(c.fields):len(c.fields)], fields...),
+	}
+}
+
+func (c *globalCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+
+	return ce
+}
+
+func (c *globalCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	g := acquireGlobal()
+	defer g.release()
+
+	if !g.options.AddCaller {
+		ent.Caller = zapcore.EntryCaller{}
+	}
+
+	if ce := g.zap.Core().Check(ent, nil); ce != nil {
+		if len(c.fields) > 0 {
+			fields = append(c.fields[:len(c.fields):len(c.fields)], fields...)
+		}
+
+		ce.Write(fields...)
+	}
+
+	return nil
+}
+
+func (c *globalCore) Sync() error {
+	g := acquireGlobal()
+	defer g.release()
+
+	return g.Sync()
+}
+
+// SetOptions replaces the global logger with a copy that has the given
+// options applied. The replaced logger is closed if it was created by
+// this package, otherwise it is only flushed. It is also left open if
+// loggers were derived from it with Named or With.
 func SetOptions(opts ...Option) {
-	global = global.WithOptions(opts...)
+	swapGlobal(func(prev *Logger) *Logger {
+		return prev.WithOptions(opts...)
+	}, true, true)
+}
+
+// ReplaceGlobal replaces the global logger with l and returns a function
+// that restores the previous one. The previous logger is flushed once
+// in-flight writes finish, but left open so that it can be restored.
+func ReplaceGlobal(l *Logger) (restore func()) {
+	prev := swapGlobal(func(*Logger) *Logger {
+		return l
+	}, false, false)
+
+	return func() {
+		swapGlobal(func(*Logger) *Logger {
+			return prev.Logger
+		}, prev.owned, true)
+	}
+}
+
+// Named returns a named child of the current global logger, see
+// Logger.Named. The child keeps using that logger if the global logger is
+// replaced later, which is then left open.
+func Named(name string) *Logger {
+	g := acquireGlobal()
+	defer g.release()
+
+	g.pin()
+
+	return g.Named(name)
+}
+
+// With returns a child of the current global logger that adds
+// keysAndValues to every entry, see Logger.With and Named.
+func With(keysAndValues ...interface{}) *Logger {
+	g := acquireGlobal()
+	defer g.release()
+
+	g.pin()
+
+	return g.With(keysAndValues...)
 }
 
 func Debug(args ...interface{}) {
-	global.debug(global.base, args...)
+	g := acquireGlobal()
+	defer g.release()
+
+	g.debug(g.base, args...)
 }
 
 func Debugf(format string, args ...interface{}) {
-	global.debugf(global.base, format, args...)
+	g := acquireGlobal()
+	defer g.release()
+
+	g.debugf(g.base, format, args...)
 }
 
 func Debugln(args ...interface{}) {
-	global.debug(global.base, sprintln(args...))
+	g := acquireGlobal()
+	defer g.release()
+
+	g.debug(g.base, sprintln(args...))
 }
 
 func Debugw(msg string, keysAndValues ...interface{}) {
-	global.debugw(global.base, msg, keysAndValues...)
+	g := acquireGlobal()
+	defer g.release()
+
+	g.debugw(g.base, msg, keysAndValues...)
 }
 
 func Info(args ...interface{}) {
-	global.info(global.base, args...)
+	g := acquireGlobal()
+	defer g.release()
+
+	g.info(g.base, args...)
 }
 
 func Infof(format string, args ...interface{}) {
-	global.infof(global.base, format, args...)
+	g := acquireGlobal()
+	defer g.release()
+
+	g.infof(g.base, format, args...)
 }
 
 func Infoln(args ...interface{}) {
-	global.info(global.base, sprintln(args...))
+	g := acquireGlobal()
+	defer g.release()
+
+	g.info(g.base, sprintln(args...))
 }
 
 func Infow(msg string, keysAndValues ...interface{}) {
-	global.infow(global.base, msg, keysAndValues...)
+	g := acquireGlobal()
+	defer g.release()
+
+	g.infow(g.base, msg, keysAndValues...)
 }
 
 func Warn(args ...interface{}) {
-	global.warn(global.base, args...)
+	g := acquireGlobal()
+	defer g.release()
+
+	g.warn(g.base, args...)
 }
 
 func Warnf(template string, args ...interface{}) {
-	global.warnf(global.base, template, args...)
+	g := acquireGlobal()
+	defer g.release()
+
+	g.warnf(g.base, template, args...)
 }
 
 func Warnln(args ...interface{}) {
-	global.warn(global.base, sprintln(args...))
+	g := acquireGlobal()
+	defer g.release()
+
+	g.warn(g.base, sprintln(args...))
 }
 
 func Warnw(msg string, keysAndValues ...interface{}) {
-	global.warnw(global.base, msg, keysAndValues...)
+	g := acquireGlobal()
+	defer g.release()
+
+	g.warnw(g.base, msg, keysAndValues...)
 }
 
 func Error(args ...interface{}) {
-	global.error(global.base, args...)
+	g := acquireGlobal()
+	defer g.release()
+
+	g.error(g.base, args...)
 }
 
 func Errorf(template string, args ...interface{}) {
-	global.errorf(global.base, template, args...)
+	g := acquireGlobal()
+	defer g.release()
+
+	g.errorf(g.base, template, args...)
 }
 
 func Errorln(args ...interface{}) {
-	global.error(global.base, sprintln(args...))
+	g := acquireGlobal()
+	defer g.release()
+
+	g.error(g.base, sprintln(args...))
 }
 
 func Errorw(msg string, keysAndValues ...interface{}) {
-	global.errorw(global.base, msg, keysAndValues...)
+	g := acquireGlobal()
+	defer g.release()
+
+	g.errorw(g.base, msg, keysAndValues...)
 }
 
 func DPanic(args ...interface{}) {
-	global.dpanic(global.base, args...)
+	g := acquireGlobal()
+	defer g.release()
+
+	g.dpanic(g.base, args...)
 }
 
 func DPanicf(template string, args ...interface{}) {
-	global.dpanicf(global.base, template, args...)
+	g := acquireGlobal()
+	defer g.release()
+
+	g.dpanicf(g.base, template, args...)
 }
 
 func DPanicln(args ...interface{}) {
-	global.dpanic(global.base, sprintln(args...))
+	g := acquireGlobal()
+	defer g.release()
+
+	g.dpanic(g.base, sprintln(args...))
 }
 
 func DPanicw(msg string, keysAndValues ...interface{}) {
-	global.dpanicw(global.base, msg, keysAndValues...)
+	g := acquireGlobal()
+	defer g.release()
+
+	g.dpanicw(g.base, msg, keysAndValues...)
 }
 
 func Panic(args ...interface{}) {
-	global.panic(global.base, args...)
+	g := acquireGlobal()
+	defer g.release()
+
+	g.panic(g.base, args...)
 }
 
 func Panicf(template string, args ...interface{}) {
-	global.panicf(global.base, template, args...)
+	g := acquireGlobal()
+	defer g.release()
+
+	g.panicf(g.base, template, args...)
 }
 
 func Panicln(args ...interface{}) {
-	global.panic(global.base, sprintln(args...))
+	g := acquireGlobal()
+	defer g.release()
+
+	g.panic(g.base, sprintln(args...))
 }
 
 func Panicw(msg string, keysAndValues ...interface{}) {
-	global.panicw(global.base, msg, keysAndValues...)
+	g := acquireGlobal()
+	defer g.release()
+
+	g.panicw(g.base, msg, keysAndValues...)
 }
 
 func Fatal(args ...interface{}) {
-	global.fatal(global.base, args...)
+	g := acquireGlobal()
+	defer g.release()
+
+	g.fatal(g.base, args...)
 }
 
 func Fatalf(template string, args ...interface{}) {
-	global.fatalf(global.base, template, args...)
+	g := acquireGlobal()
+	defer g.release()
+
+	g.fatalf(g.base, template, args...)
 }
 
 func Fatalln(args ...interface{}) {
-	global.fatal(global.base, sprintln(args...))
+	g := acquireGlobal()
+	defer g.release()
+
+	g.fatal(g.base, sprintln(args...))
 }
 
 func Fatalw(msg string, keysAndValues ...interface{}) {
-	global.fatalw(global.base, msg, keysAndValues...)
+	g := acquireGlobal()
+	defer g.release()
+
+	g.fatalw(g.base, msg, keysAndValues...)
+}
+
+// DebugFields logs a message with typed fields, see Logger.DebugFields.
+func DebugFields(msg string, fields ...Field) {
+	g := acquireGlobal()
+	defer g.release()
+
+	if ce := g.zap.Check(zapcore.DebugLevel, msg); ce != nil {
+		ce.Write(copyFields(fields)...)
+	}
+}
+
+func InfoFields(msg string, fields ...Field) {
+	g := acquireGlobal()
+	defer g.release()
+
+	if ce := g.zap.Check(zapcore.InfoLevel, msg); ce != nil {
+		ce.Write(copyFields(fields)...)
+	}
+}
+
+func WarnFields(msg string, fields ...Field) {
+	g := acquireGlobal()
+	defer g.release()
+
+	if ce := g.zap.Check(zapcore.WarnLevel, msg); ce != nil {
+		ce.Write(copyFields(fields)...)
+	}
+}
+
+func ErrorFields(msg string, fields ...Field) {
+	g := acquireGlobal()
+	defer g.release()
+
+	if ce := g.zap.Check(zapcore.ErrorLevel, msg); ce != nil {
+		ce.Write(copyFields(fields)...)
+	}
+}
+
+func DPanicFields(msg string, fields ...Field) {
+	g := acquireGlobal()
+	defer g.release()
+
+	if ce := g.zap.Check(zapcore.DPanicLevel, msg); ce != nil {
+		ce.Write(copyFields(fields)...)
+	}
+}
+
+func PanicFields(msg string, fields ...Field) {
+	g := acquireGlobal()
+	defer g.release()
+
+	if ce := g.zap.Check(zapcore.PanicLevel, msg); ce != nil {
+		ce.Write(copyFields(fields)...)
+	}
+}
+
+func FatalFields(msg string, fields ...Field) {
+	g := acquireGlobal()
+	defer g.release()
+
+	if ce := g.zap.Check(zapcore.FatalLevel, msg); ce != nil {
+		ce.Write(copyFields(fields)...)
+	}
+}
+
+// event returns an Event for ce. Events write through g after the package
+// function returned, so g is left open until they are written if it is
+// replaced in the meantime. It must be called between acquireGlobal and
+// release.
+func (g *globalLogger) event(ce *zapcore.CheckedEntry) *Event {
+	if ce == nil {
+		return nil
+	}
+
+	atomic.AddInt32(&g.events, 1)
+
+	e := newEvent(ce)
+	e.global = g
+
+	return e
+}
+
+// DebugEvent starts an entry of the global logger, see Logger.DebugEvent.
+// The logger stays open for the event if the global logger is replaced,
+// until the event is written by Msg, Msgf or Send.
+func DebugEvent() *Event {
+	g := acquireGlobal()
+	defer g.release()
+
+	return g.event(g.zap.Check(zapcore.DebugLevel, ""))
+}
+
+func InfoEvent() *Event {
+	g := acquireGlobal()
+	defer g.release()
+
+	return g.event(g.zap.Check(zapcore.InfoLevel, ""))
+}
+
+func WarnEvent() *Event {
+	g := acquireGlobal()
+	defer g.release()
+
+	return g.event(g.zap.Check(zapcore.WarnLevel, ""))
+}
+
+func ErrorEvent() *Event {
+	g := acquireGlobal()
+	defer g.release()
+
+	return g.event(g.zap.Check(zapcore.ErrorLevel, ""))
+}
+
+func DPanicEvent() *Event {
+	g := acquireGlobal()
+	defer g.release()
+
+	return g.event(g.zap.Check(zapcore.DPanicLevel, ""))
+}
+
+func PanicEvent() *Event {
+	g := acquireGlobal()
+	defer g.release()
+
+	return g.event(g.zap.Check(zapcore.PanicLevel, ""))
+}
+
+func FatalEvent() *Event {
+	g := acquireGlobal()
+	defer g.release()
+
+	return g.event(g.zap.Check(zapcore.FatalLevel, ""))
+}
+
+// DebugCtx logs with the logger carried by ctx, or the global logger, see
+// Logger.DebugCtx.
+func DebugCtx(ctx context.Context, msg string, keysAndValues ...interface{}) {
+	l, release := contextLogger(ctx)
+	defer release()
+
+	if ce := l.zap.Check(zapcore.DebugLevel, msg); ce != nil {
+		ce.Write(keysAndValuesFields(contextKeysAndValues(ctx, keysAndValues))...)
+	}
+}
+
+func InfoCtx(ctx context.Context, msg string, keysAndValues ...interface{}) {
+	l, release := contextLogger(ctx)
+	defer release()
+
+	if ce := l.zap.Check(zapcore.InfoLevel, msg); ce != nil {
+		ce.Write(keysAndValuesFields(contextKeysAndValues(ctx, keysAndValues))...)
+	}
+}
+
+func WarnCtx(ctx context.Context, msg string, keysAndValues ...interface{}) {
+	l, release := contextLogger(ctx)
+	defer release()
+
+	if ce := l.zap.Check(zapcore.WarnLevel, msg); ce != nil {
+		ce.Write(keysAndValuesFields(contextKeysAndValues(ctx, keysAndValues))...)
+	}
+}
+
+func ErrorCtx(ctx context.Context, msg string, keysAndValues ...interface{}) {
+	l, release := contextLogger(ctx)
+	defer release()
+
+	if ce := l.zap.Check(zapcore.ErrorLevel, msg); ce != nil {
+		ce.Write(keysAndValuesFields(contextKeysAndValues(ctx, keysAndValues))...)
+	}
+}
+
+func DPanicCtx(ctx context.Context, msg string, keysAndValues ...interface{}) {
+	l, release := contextLogger(ctx)
+	defer release()
+
+	if ce := l.zap.Check(zapcore.DPanicLevel, msg); ce != nil {
+		ce.Write(keysAndValuesFields(contextKeysAndValues(ctx, keysAndValues))...)
+	}
+}
+
+func PanicCtx(ctx context.Context, msg string, keysAndValues ...interface{}) {
+	l, release := contextLogger(ctx)
+	defer release()
+
+	if ce := l.zap.Check(zapcore.PanicLevel, msg); ce != nil {
+		ce.Write(keysAndValuesFields(contextKeysAndValues(ctx, keysAndValues))...)
+	}
+}
+
+func FatalCtx(ctx context.Context, msg string, keysAndValues ...interface{}) {
+	l, release := contextLogger(ctx)
+	defer release()
+
+	if ce := l.zap.Check(zapcore.FatalLevel, msg); ce != nil {
+		ce.Write(keysAndValuesFields(contextKeysAndValues(ctx, keysAndValues))...)
+	}
 }
 
 func Rotate() error {
-	return global.Rotate()
+	return loadGlobal().Rotate()
+}
+
+func Reopen() error {
+	return loadGlobal().Reopen()
+}
+
+func RotateFile(path string) error {
+	return loadGlobal().RotateFile(path)
+}
+
+func Sync() error {
+	return loadGlobal().Sync()
+}
+
+func Close() error {
+	return loadGlobal().Close()
+}
+
+// SetLevel changes the minimum level of the global logger at runtime.
+func SetLevel(lvl Level) {
+	loadGlobal().SetLevel(lvl)
+}
+
+// GetLevel returns the minimum level of the global logger.
+func GetLevel() Level {
+	return loadGlobal().Level()
+}
+
+// SetLevelSpec changes the levels of the named loggers derived from the
+// global logger at runtime.
+func SetLevelSpec(spec LevelSpec) {
+	loadGlobal().SetLevelSpec(spec)
+}
+
+// Enabled reports whether the global logger writes entries at lvl.
+func Enabled(lvl Level) bool {
+	return loadGlobal().Enabled(lvl)
+}
+
+// V returns a Verbose of the global logger, see Logger.V. The level is
+// checked against the current global logger, the entries are written
+// through the global logger at the time of each entry.
+func V(level int) Verbose {
+	g := acquireGlobal()
+	defer g.release()
+
+	if g.V(level).l == nil {
+		return Verbose{}
+	}
+
+	return Verbose{global: true}
+}
+
+// SetVerbosity changes the verbosity of the global logger at runtime.
+func SetVerbosity(v int) {
+	loadGlobal().SetVerbosity(v)
 }
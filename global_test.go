@@ -1,6 +1,10 @@
 package log
 
 import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"sync"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -70,3 +74,131 @@ func TestPanic(t *testing.T) {
 		Panic("global: panic ", "this should panic")
 	})
 }
+
+func TestReplaceGlobal(t *testing.T) {
+	var buf bytes.Buffer
+
+	l := New(WithLogToStdout(false), WithOutput(&buf), WithFormat(FormatConsole))
+	restore := ReplaceGlobal(l)
+
+	Info("replaced")
+	assert.Contains(t, buf.String(), "replaced")
+
+	restore()
+	buf.Reset()
+
+	Info("restored")
+	assert.Empty(t, buf.String())
+}
+
+func TestReplaceGlobal_RestoreFiles(t *testing.T) {
+	defer ReplaceGlobal(New(WithLogToStdout(false)))()
+
+	file := filepath.Join(tempDir(t), "global.log")
+	SetOptions(WithLogFiles(file))
+
+	Info("before")
+
+	restore := ReplaceGlobal(New(WithLogToStdout(false)))
+	Info("replaced")
+	restore()
+
+	Info("after")
+	assert.NoError(t, Sync())
+
+	content := readFile(t, file)
+	assert.Contains(t, content, "before")
+	assert.NotContains(t, content, "replaced")
+	assert.Contains(t, content, "after")
+}
+
+func TestSetOptionsConcurrent(t *testing.T) {
+	defer ReplaceGlobal(New())()
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for j := 0; j < 100; j++ {
+				Debugw("concurrent", "j", j)
+			}
+		}()
+	}
+
+	for i := 0; i < 10; i++ {
+		SetOptions(WithLogToStdout(false), WithLevel(Level(i%2)))
+	}
+
+	wg.Wait()
+}
+
+func TestSetOptionsClosesReplaced(t *testing.T) {
+	dir := tempDir(t)
+	file := filepath.Join(dir, "global.log")
+
+	defer ReplaceGlobal(New())()
+
+	SetOptions(WithLogToStdout(false), WithLogFiles(file))
+	Info("first")
+
+	SetOptions(WithLogFiles())
+	assert.NoError(t, os.Remove(file))
+
+	Info("second")
+
+	_, err := os.Stat(file)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestSetLevel(t *testing.T) {
+	var buf bytes.Buffer
+
+	restore := ReplaceGlobal(New(WithLogToStdout(false), WithOutput(&buf)))
+	defer restore()
+
+	SetLevel(DebugLevel)
+	assert.Equal(t, DebugLevel, GetLevel())
+
+	Debug("debug entry")
+	assert.Contains(t, buf.String(), "debug entry")
+
+	SetOptions(AddCaller())
+	assert.Equal(t, DebugLevel, GetLevel())
+}
+
+func TestWithNamed(t *testing.T) {
+	var buf bytes.Buffer
+
+	restore := ReplaceGlobal(New(WithLogToStdout(false), WithOutput(&buf)))
+	defer restore()
+
+	Named("http").With("method", "GET").Info("request")
+	With("id", 1).Info("job")
+
+	assert.Contains(t, buf.String(), `"logger":"http","msg":"request","method":"GET"`)
+	assert.Contains(t, buf.String(), `"msg":"job","id":1`)
+}
+
+func TestWithNamed_SetOptions(t *testing.T) {
+	defer ReplaceGlobal(New(WithLogToStdout(false)))()
+
+	file := filepath.Join(tempDir(t), "global.log")
+	SetOptions(WithLogFiles(file))
+
+	db := Named("db")
+	job := With("id", 1)
+
+	SetOptions(AddCaller())
+
+	db.Info("db entry")
+	job.Info("job entry")
+	assert.NoError(t, db.Sync())
+
+	content := readFile(t, file)
+	assert.Contains(t, content, "db entry")
+	assert.Contains(t, content, "job entry")
+}
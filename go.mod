@@ -1,12 +1,28 @@
 module go.kuoruan.net/log
 
-go 1.13
+go 1.21
 
 require (
-	github.com/natefinch/lumberjack v0.0.0-20201021141957-47ffae23317c
+	github.com/go-kit/log v0.2.1
+	github.com/go-logr/logr v1.2.3
+	github.com/hashicorp/go-hclog v1.5.0
 	github.com/stretchr/testify v1.8.0
+	go.opentelemetry.io/otel/trace v1.10.0
+	go.uber.org/multierr v1.6.0
 	go.uber.org/zap v1.23.0
-	gopkg.in/yaml.v2 v2.4.0 // indirect
+)
+
+require (
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/fatih/color v1.13.0 // indirect
+	github.com/go-logfmt/logfmt v0.5.1 // indirect
+	github.com/mattn/go-colorable v0.1.12 // indirect
+	github.com/mattn/go-isatty v0.0.14 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	go.opentelemetry.io/otel v1.10.0 // indirect
+	go.uber.org/atomic v1.7.0 // indirect
+	golang.org/x/sys v0.10.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
 
 retract (
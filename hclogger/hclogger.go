@@ -0,0 +1,232 @@
+// Package hclogger implements hclog.Logger on top of go.kuoruan.net/log,
+// e.g. for HashiCorp libraries.
+package hclogger
+
+import (
+	"io"
+	stdlog "log"
+	"regexp"
+	"strings"
+
+	"github.com/hashicorp/go-hclog"
+
+	"go.kuoruan.net/log"
+)
+
+// TraceLevel is the level of hclog.Trace, the verbose debug level right
+// below DebugLevel.
+const TraceLevel = log.DebugLevel - 1
+
+type logger struct {
+	root *log.Logger
+	l    *log.Logger
+	name string
+	args []interface{}
+}
+
+var _ hclog.Logger = (*logger)(nil)
+
+// New returns an hclog.Logger that writes to l. SetLevel changes the level
+// of l, hclog.Off is FatalLevel.
+func New(l *log.Logger) hclog.Logger {
+	// skip the method of the logger
+	l = l.WithCallerSkip(1)
+
+	return &logger{root: l, l: l}
+}
+
+func (h *logger) Log(level hclog.Level, msg string, args ...interface{}) {
+	if level == hclog.Off {
+		return
+	}
+
+	h.l.Logw(fromHclogLevel(level), msg, args...)
+}
+
+func (h *logger) Trace(msg string, args ...interface{}) {
+	h.l.Logw(TraceLevel, msg, args...)
+}
+
+func (h *logger) Debug(msg string, args ...interface{}) {
+	h.l.Logw(log.DebugLevel, msg, args...)
+}
+
+func (h *logger) Info(msg string, args ...interface{}) {
+	h.l.Logw(log.InfoLevel, msg, args...)
+}
+
+func (h *logger) Warn(msg string, args ...interface{}) {
+	h.l.Logw(log.WarnLevel, msg, args...)
+}
+
+func (h *logger) Error(msg string, args ...interface{}) {
+	h.l.Logw(log.ErrorLevel, msg, args...)
+}
+
+func (h *logger) IsTrace() bool {
+	return h.l.Enabled(TraceLevel)
+}
+
+func (h *logger) IsDebug() bool {
+	return h.l.Enabled(log.DebugLevel)
+}
+
+func (h *logger) IsInfo() bool {
+	return h.l.Enabled(log.InfoLevel)
+}
+
+func (h *logger) IsWarn() bool {
+	return h.l.Enabled(log.WarnLevel)
+}
+
+func (h *logger) IsError() bool {
+	return h.l.Enabled(log.ErrorLevel)
+}
+
+func (h *logger) ImpliedArgs() []interface{} {
+	args := make([]interface{}, len(h.args))
+	copy(args, h.args)
+
+	return args
+}
+
+func (h *logger) With(args ...interface{}) hclog.Logger {
+	c := *h
+	c.l = h.l.With(args...)
+	c.args = append(h.ImpliedArgs(), args...)
+
+	return &c
+}
+
+func (h *logger) Name() string {
+	return h.name
+}
+
+func (h *logger) Named(name string) hclog.Logger {
+	c := *h
+	c.l = h.l.Named(name)
+	c.name = name
+
+	if h.name != "" {
+		c.name = h.name + "." + name
+	}
+
+	return &c
+}
+
+func (h *logger) ResetNamed(name string) hclog.Logger {
+	c := *h
+	c.l = h.root.Named(name).With(h.args...)
+	c.name = name
+
+	return &c
+}
+
+func (h *logger) SetLevel(level hclog.Level) {
+	h.l.SetLevel(fromHclogLevel(level))
+}
+
+func (h *logger) GetLevel() hclog.Level {
+	return toHclogLevel(h.l.Level())
+}
+
+func (h *logger) StandardLogger(opts *hclog.StandardLoggerOptions) *stdlog.Logger {
+	return stdlog.New(h.StandardWriter(opts), "", 0)
+}
+
+func (h *logger) StandardWriter(opts *hclog.StandardLoggerOptions) io.Writer {
+	if opts == nil {
+		opts = &hclog.StandardLoggerOptions{}
+	}
+
+	// skip the stdlib logger as well
+	return &stdWriter{
+		l:    h.l.WithCallerSkip(2),
+		opts: *opts,
+	}
+}
+
+func fromHclogLevel(level hclog.Level) log.Level {
+	switch level {
+	case hclog.Trace:
+		return TraceLevel
+	case hclog.Debug:
+		return log.DebugLevel
+	case hclog.Warn:
+		return log.WarnLevel
+	case hclog.Error:
+		return log.ErrorLevel
+	case hclog.Off:
+		return log.FatalLevel
+	}
+
+	return log.InfoLevel
+}
+
+func toHclogLevel(lvl log.Level) hclog.Level {
+	switch {
+	case lvl < log.DebugLevel:
+		return hclog.Trace
+	case lvl == log.DebugLevel:
+		return hclog.Debug
+	case lvl == log.InfoLevel:
+		return hclog.Info
+	case lvl == log.WarnLevel:
+		return hclog.Warn
+	}
+
+	return hclog.Error
+}
+
+// timestampRegexp matches the characters of common timestamp formats at
+// the beginning of a line, like hclog does.
+var timestampRegexp = regexp.MustCompile(`^[\d\s\:\/\.\+-TZ]*`)
+
+// stdWriter takes the lines of a stdlib logger, with the level handling
+// of hclog.StandardLoggerOptions.
+type stdWriter struct {
+	l    *log.Logger
+	opts hclog.StandardLoggerOptions
+}
+
+func (w *stdWriter) Write(p []byte) (int, error) {
+	line := strings.TrimRight(string(p), " \t\n")
+	level := log.InfoLevel
+
+	switch {
+	case w.opts.ForceLevel != hclog.NoLevel:
+		_, line = pickLevel(line)
+		level = fromHclogLevel(w.opts.ForceLevel)
+	case w.opts.InferLevels:
+		if w.opts.InferLevelsWithTimestamp {
+			line = line[timestampRegexp.FindStringIndex(line)[1]:]
+		}
+
+		level, line = pickLevel(line)
+	}
+
+	w.l.Logw(level, line)
+
+	return len(p), nil
+}
+
+// pickLevel detects the level of a line by its "[LEVEL]" prefix.
+func pickLevel(line string) (log.Level, string) {
+	for _, prefix := range []struct {
+		tag   string
+		level log.Level
+	}{
+		{"[TRACE]", TraceLevel},
+		{"[DEBUG]", log.DebugLevel},
+		{"[INFO]", log.InfoLevel},
+		{"[WARN]", log.WarnLevel},
+		{"[ERROR]", log.ErrorLevel},
+		{"[ERR]", log.ErrorLevel},
+	} {
+		if strings.HasPrefix(line, prefix.tag) {
+			return prefix.level, strings.TrimSpace(line[len(prefix.tag):])
+		}
+	}
+
+	return log.InfoLevel, line
+}
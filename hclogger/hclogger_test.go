@@ -0,0 +1,83 @@
+package hclogger
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/stretchr/testify/assert"
+
+	"go.kuoruan.net/log"
+)
+
+func TestNew(t *testing.T) {
+	var buf bytes.Buffer
+
+	l := log.New(log.WithLogToStdout(false), log.WithOutput(&buf), log.AddCaller())
+	logger := New(l).Named("raft").With("node", "a")
+
+	logger.Info("elected", "term", 2)
+	assert.Contains(t, buf.String(), `"level":"info"`)
+	assert.Contains(t, buf.String(), `"logger":"raft"`)
+	assert.Contains(t, buf.String(), `/hclogger_test.go:`)
+	assert.Contains(t, buf.String(), `"msg":"elected","node":"a","term":2`)
+
+	assert.Equal(t, "raft", logger.Name())
+	assert.Equal(t, "raft.peer", logger.Named("peer").Name())
+	assert.Equal(t, []interface{}{"node", "a"}, logger.ImpliedArgs())
+
+	buf.Reset()
+	logger.ResetNamed("serf").Log(hclog.Warn, "reset")
+	assert.Contains(t, buf.String(), `"level":"warn"`)
+	assert.Contains(t, buf.String(), `"logger":"serf"`)
+	assert.Contains(t, buf.String(), `/hclogger_test.go:`)
+	assert.Contains(t, buf.String(), `"msg":"reset","node":"a"`)
+}
+
+func TestLevels(t *testing.T) {
+	var buf bytes.Buffer
+
+	logger := New(log.New(log.WithLogToStdout(false), log.WithOutput(&buf)))
+
+	assert.Equal(t, hclog.Info, logger.GetLevel())
+	assert.False(t, logger.IsDebug())
+	assert.True(t, logger.IsInfo())
+
+	logger.Trace("dropped")
+	assert.Empty(t, buf.String())
+
+	logger.SetLevel(hclog.Trace)
+	assert.Equal(t, hclog.Trace, logger.GetLevel())
+	assert.True(t, logger.IsTrace())
+
+	logger.Trace("trace")
+	assert.Contains(t, buf.String(), `"msg":"trace"`)
+
+	logger.SetLevel(hclog.Error)
+	assert.False(t, logger.IsWarn())
+	assert.True(t, logger.IsError())
+}
+
+func TestStandardLogger(t *testing.T) {
+	var buf bytes.Buffer
+
+	logger := New(log.New(log.WithLogToStdout(false), log.WithOutput(&buf), log.AddCaller()))
+
+	std := logger.StandardLogger(&hclog.StandardLoggerOptions{InferLevels: true})
+	std.Println("[WARN] disk almost full")
+	assert.Contains(t, buf.String(), `"level":"warn"`)
+	assert.Contains(t, buf.String(), `/hclogger_test.go:`)
+	assert.Contains(t, buf.String(), `"msg":"disk almost full"}`)
+
+	buf.Reset()
+	std = logger.StandardLogger(&hclog.StandardLoggerOptions{ForceLevel: hclog.Error})
+	std.Println("[INFO] forced")
+	assert.Contains(t, buf.String(), `"level":"error"`)
+	assert.Contains(t, buf.String(), `"msg":"forced"}`)
+
+	buf.Reset()
+	std = logger.StandardLogger(&hclog.StandardLoggerOptions{InferLevels: true, InferLevelsWithTimestamp: true})
+	std.Println("2022/01/02 15:04:05 [ERR] timestamped")
+	assert.Contains(t, buf.String(), `"level":"error"`)
+	assert.Contains(t, buf.String(), `"msg":"timestamped"}`)
+}
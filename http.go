@@ -0,0 +1,176 @@
+package log
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// levelHandler serves the level of a logger, see LevelHandler.
+type levelHandler struct {
+	logger *Logger
+
+	mu      sync.Mutex
+	timer   *time.Timer
+	target  *Logger
+	revert  Level
+	expires time.Time
+}
+
+type levelRequest struct {
+	Level string `json:"level"`
+	TTL   string `json:"ttl"`
+}
+
+type levelResponse struct {
+	Level   string     `json:"level"`
+	Revert  string     `json:"revert,omitempty"`
+	Expires *time.Time `json:"expires,omitempty"`
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// LevelHandler returns an http.Handler for the level of l. A nil l stands
+// for the global logger at the time of the request.
+//
+// GET reports the level as {"level":"info"}. PUT and POST change it, with
+// either a JSON body {"level":"debug"} or the form field level. An
+// optional ttl, e.g. "10m", elevates the level temporarily: it reverts to
+// the level before the first temporary change once the ttl has passed,
+// unless it is changed again in between.
+func LevelHandler(l *Logger) http.Handler {
+	return &levelHandler{logger: l}
+}
+
+func (h *levelHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		h.writeLevel(w, h.resolve())
+	case http.MethodPut, http.MethodPost:
+		req, err := decodeLevelRequest(r)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: err.Error()})
+			return
+		}
+
+		lvl, err := ParseLevel(req.Level)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: err.Error()})
+			return
+		}
+
+		var ttl time.Duration
+
+		if req.TTL != "" {
+			if ttl, err = time.ParseDuration(req.TTL); err != nil || ttl <= 0 {
+				writeJSON(w, http.StatusBadRequest, errorResponse{Error: fmt.Sprintf("not a valid ttl: %q", req.TTL)})
+				return
+			}
+		}
+
+		l := h.resolve()
+		h.setLevel(l, lvl, ttl)
+		h.writeLevel(w, l)
+	default:
+		w.Header().Set("Allow", "GET, PUT, POST")
+		writeJSON(w, http.StatusMethodNotAllowed, errorResponse{Error: "only GET, PUT and POST are supported"})
+	}
+}
+
+func (h *levelHandler) resolve() *Logger {
+	if h.logger != nil {
+		return h.logger
+	}
+
+	return loadGlobal()
+}
+
+func (h *levelHandler) setLevel(l *Logger, lvl Level, ttl time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	revert := l.Level()
+
+	if h.timer != nil {
+		h.timer.Stop()
+		h.timer = nil
+
+		// keep reverting to the level before the first temporary change
+		if h.target == l {
+			revert = h.revert
+		}
+	}
+
+	l.SetLevel(lvl)
+
+	if ttl <= 0 {
+		return
+	}
+
+	var timer *time.Timer
+
+	timer = time.AfterFunc(ttl, func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+
+		if h.timer != timer {
+			return
+		}
+
+		h.timer = nil
+		l.SetLevel(revert)
+	})
+
+	h.timer = timer
+	h.target = l
+	h.revert = revert
+	h.expires = time.Now().Add(ttl)
+}
+
+func (h *levelHandler) writeLevel(w http.ResponseWriter, l *Logger) {
+	h.mu.Lock()
+	resp := levelResponse{Level: l.Level().String()}
+
+	if h.timer != nil && h.target == l {
+		expires := h.expires
+		resp.Revert = h.revert.String()
+		resp.Expires = &expires
+	}
+	h.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func decodeLevelRequest(r *http.Request) (levelRequest, error) {
+	var req levelRequest
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+
+	switch mediaType {
+	case "application/x-www-form-urlencoded", "multipart/form-data":
+		req.Level = r.FormValue("level")
+		req.TTL = r.FormValue("ttl")
+	default:
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			return req, fmt.Errorf("invalid request body: %v", err)
+		}
+	}
+
+	if req.Level == "" {
+		return req, errors.New("missing level")
+	}
+
+	return req, nil
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(v)
+}
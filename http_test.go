@@ -0,0 +1,118 @@
+package log
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func serveLevel(t *testing.T, h http.Handler, method, contentType, body string) (int, map[string]interface{}) {
+	t.Helper()
+
+	req := httptest.NewRequest(method, "/log/level", strings.NewReader(body))
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+
+	var resp map[string]interface{}
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+
+	return rec.Code, resp
+}
+
+func TestLevelHandler(t *testing.T) {
+	l := New(WithLogToStdout(false))
+	h := LevelHandler(l)
+
+	code, resp := serveLevel(t, h, http.MethodGet, "", "")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, map[string]interface{}{"level": "info"}, resp)
+
+	code, resp = serveLevel(t, h, http.MethodPut, "application/json", `{"level":"debug"}`)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "debug", resp["level"])
+	assert.Equal(t, DebugLevel, l.Level())
+
+	form := url.Values{"level": {"warn"}}.Encode()
+	code, resp = serveLevel(t, h, http.MethodPost, "application/x-www-form-urlencoded", form)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "warn", resp["level"])
+	assert.Equal(t, WarnLevel, l.Level())
+}
+
+func TestLevelHandler_Errors(t *testing.T) {
+	l := New(WithLogToStdout(false))
+	h := LevelHandler(l)
+
+	for _, tt := range []struct {
+		method, contentType, body string
+		code                      int
+	}{
+		{http.MethodPut, "application/json", `{"level":"verbose"}`, http.StatusBadRequest},
+		{http.MethodPut, "application/json", `{}`, http.StatusBadRequest},
+		{http.MethodPut, "application/json", `level=debug`, http.StatusBadRequest},
+		{http.MethodPut, "application/json", `{"level":"debug","ttl":"soon"}`, http.StatusBadRequest},
+		{http.MethodPut, "application/json", `{"level":"debug","ttl":"-1s"}`, http.StatusBadRequest},
+		{http.MethodDelete, "", "", http.StatusMethodNotAllowed},
+	} {
+		code, resp := serveLevel(t, h, tt.method, tt.contentType, tt.body)
+		assert.Equal(t, tt.code, code, tt.body)
+		assert.NotEmpty(t, resp["error"], tt.body)
+	}
+
+	assert.Equal(t, InfoLevel, l.Level())
+}
+
+func TestLevelHandler_TTL(t *testing.T) {
+	l := New(WithLogToStdout(false))
+	h := LevelHandler(l)
+
+	code, resp := serveLevel(t, h, http.MethodPut, "application/json", `{"level":"debug","ttl":"1h"}`)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "debug", resp["level"])
+	assert.Equal(t, "info", resp["revert"])
+	assert.NotEmpty(t, resp["expires"])
+
+	// a second temporary change still reverts to the original level
+	form := url.Values{"level": {"warn"}, "ttl": {"50ms"}}.Encode()
+	_, resp = serveLevel(t, h, http.MethodPost, "application/x-www-form-urlencoded", form)
+	assert.Equal(t, "warn", resp["level"])
+	assert.Equal(t, "info", resp["revert"])
+
+	assert.Eventually(t, func() bool {
+		return l.Level() == InfoLevel
+	}, time.Second, 10*time.Millisecond)
+
+	_, resp = serveLevel(t, h, http.MethodGet, "", "")
+	assert.Equal(t, map[string]interface{}{"level": "info"}, resp)
+}
+
+func TestLevelHandler_PermanentChangeCancelsTTL(t *testing.T) {
+	l := New(WithLogToStdout(false))
+	h := LevelHandler(l)
+
+	serveLevel(t, h, http.MethodPut, "application/json", `{"level":"debug","ttl":"20ms"}`)
+	serveLevel(t, h, http.MethodPut, "application/json", `{"level":"error"}`)
+
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, ErrorLevel, l.Level())
+}
+
+func TestLevelHandler_Global(t *testing.T) {
+	restore := ReplaceGlobal(New(WithLogToStdout(false)))
+	defer restore()
+
+	serveLevel(t, LevelHandler(nil), http.MethodPut, "application/json", `{"level":"error"}`)
+	assert.Equal(t, ErrorLevel, GetLevel())
+}
@@ -0,0 +1,106 @@
+// Package kitlog implements the go-kit log.Logger interface on top of
+// go.kuoruan.net/log.
+package kitlog
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+
+	kitlog "github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+
+	"go.kuoruan.net/log"
+)
+
+// MessageKey is the key whose value becomes the message of an entry.
+const MessageKey = "msg"
+
+type logger struct {
+	l *log.Logger
+}
+
+// New returns a go-kit logger that writes to l. The level of an entry is
+// taken from the value set by the go-kit level package, InfoLevel if there
+// is none.
+//
+// The reported caller is the first one outside of go-kit, so that entries
+// logged through wrappers such as level.Info or log.With point to the code
+// that logged them.
+func New(l *log.Logger) kitlog.Logger {
+	// skip the method of the logger
+	return &logger{l: l.WithCallerSkip(1)}
+}
+
+func (k *logger) Log(keyvals ...interface{}) error {
+	lvl := log.InfoLevel
+	msg := ""
+	kvs := make([]interface{}, 0, len(keyvals))
+
+	for i := 0; i < len(keyvals); i += 2 {
+		key := keyvals[i]
+
+		var value interface{} = kitlog.ErrMissingValue
+		if i+1 < len(keyvals) {
+			value = keyvals[i+1]
+		}
+
+		if v, ok := value.(level.Value); ok {
+			if parsed, err := log.ParseLevel(v.String()); err == nil {
+				lvl = parsed
+				continue
+			}
+		}
+
+		name, ok := key.(string)
+		if !ok {
+			name = fmt.Sprint(key)
+		}
+
+		if name == MessageKey && msg == "" {
+			msg = fmt.Sprint(value)
+			continue
+		}
+
+		kvs = append(kvs, name, value)
+	}
+
+	l := k.l
+	if skip := kitFrames(); skip > 0 {
+		l = l.WithCallerSkip(skip)
+	}
+
+	l.Logw(lvl, msg, kvs...)
+
+	return nil
+}
+
+// kitPackage prefixes the functions of the go-kit log packages.
+const kitPackage = "github.com/go-kit/log"
+
+// maxKitFrames bounds the go-kit frames looked at above Log.
+const maxKitFrames = 8
+
+// kitFrames returns the number of go-kit frames between the caller of Log
+// and the first caller outside of go-kit.
+func kitFrames() int {
+	pcs := make([]uintptr, maxKitFrames)
+	// skip runtime.Callers, kitFrames and Log
+	pcs = pcs[:runtime.Callers(3, pcs)]
+
+	n := 0
+
+	frames := runtime.CallersFrames(pcs)
+	for {
+		frame, more := frames.Next()
+		if !strings.HasPrefix(frame.Function, kitPackage+".") && !strings.HasPrefix(frame.Function, kitPackage+"/") {
+			return n
+		}
+
+		n++
+
+		if !more {
+			return n
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package kitlog
+
+import (
+	"bytes"
+	"testing"
+
+	kitlog "github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/stretchr/testify/assert"
+
+	"go.kuoruan.net/log"
+)
+
+func TestNew(t *testing.T) {
+	var buf bytes.Buffer
+
+	l := log.New(log.WithLogToStdout(false), log.WithOutput(&buf), log.AddCaller())
+	logger := New(l)
+
+	assert.NoError(t, logger.Log("msg", "started", "port", 8080, 42, "odd"))
+	assert.Contains(t, buf.String(), `"level":"info"`)
+	assert.Contains(t, buf.String(), `/kitlog_test.go:`)
+	assert.Contains(t, buf.String(), `"msg":"started","port":8080,"42":"odd"`)
+
+	buf.Reset()
+	assert.NoError(t, level.Debug(logger).Log("msg", "dropped"))
+	assert.NoError(t, level.Warn(logger).Log("msg", "slow", "missing"))
+	assert.NotContains(t, buf.String(), "dropped")
+	assert.Contains(t, buf.String(), `"level":"warn"`)
+	assert.Contains(t, buf.String(), `/kitlog_test.go:`)
+	assert.Contains(t, buf.String(), `"msg":"slow","missing":"`+kitlog.ErrMissingValue.Error()+`"`)
+
+	buf.Reset()
+	assert.NoError(t, level.Info(kitlog.With(logger, "component", "db")).Log("msg", "wrapped"))
+	assert.Contains(t, buf.String(), `/kitlog_test.go:`)
+	assert.Contains(t, buf.String(), `"msg":"wrapped","component":"db"`)
+}
@@ -2,6 +2,7 @@ package log
 
 import (
 	"fmt"
+	"math"
 	"strings"
 
 	"go.uber.org/zap/zapcore"
@@ -23,6 +24,12 @@ func (l Level) Enabled(lvl Level) bool {
 	return lvl >= l
 }
 
+// String returns the lower-case name of the level, as accepted by
+// ParseLevel.
+func (l Level) String() string {
+	return toZapLevel(l).String()
+}
+
 func ParseLevel(lvl string) (Level, error) {
 	switch strings.ToLower(lvl) {
 	case "fatal":
@@ -44,44 +51,20 @@ func ParseLevel(lvl string) (Level, error) {
 	return InfoLevel, fmt.Errorf("not a valid Level: %q", lvl)
 }
 
+// fromZapLevel and toZapLevel convert between the levels of this package
+// and zap, which share their values. Levels below DebugLevel are more
+// verbose debug levels.
 func fromZapLevel(lvl zapcore.Level) Level {
-	switch lvl {
-	case zapcore.DebugLevel:
-		return DebugLevel
-	case zapcore.InfoLevel:
-		return InfoLevel
-	case zapcore.WarnLevel:
-		return WarnLevel
-	case zapcore.ErrorLevel:
-		return ErrorLevel
-	case zapcore.DPanicLevel:
-		return DPanicLevel
-	case zapcore.PanicLevel:
-		return PanicLevel
-	case zapcore.FatalLevel:
-		return FatalLevel
-	}
-
-	return InfoLevel
+	return Level(lvl)
 }
 
 func toZapLevel(lvl Level) zapcore.Level {
-	switch lvl {
-	case DebugLevel:
-		return zapcore.DebugLevel
-	case InfoLevel:
-		return zapcore.InfoLevel
-	case WarnLevel:
-		return zapcore.WarnLevel
-	case ErrorLevel:
-		return zapcore.ErrorLevel
-	case DPanicLevel:
-		return zapcore.DPanicLevel
-	case PanicLevel:
-		return zapcore.PanicLevel
-	case FatalLevel:
+	switch {
+	case lvl < math.MinInt8:
+		return zapcore.Level(math.MinInt8)
+	case lvl > FatalLevel:
 		return zapcore.FatalLevel
 	}
 
-	return zapcore.InfoLevel
+	return zapcore.Level(lvl)
 }
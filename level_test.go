@@ -49,3 +49,14 @@ func TestParseLevel(t *testing.T) {
 		assert.Equal(t, lvl.expected, ll)
 	}
 }
+
+func TestZapLevel(t *testing.T) {
+	for lvl := DebugLevel; lvl <= FatalLevel; lvl++ {
+		assert.Equal(t, lvl.String(), toZapLevel(lvl).String())
+		assert.Equal(t, lvl, fromZapLevel(toZapLevel(lvl)))
+	}
+
+	// verbose debug levels
+	assert.Equal(t, Level(-3), fromZapLevel(toZapLevel(Level(-3))))
+	assert.Equal(t, FatalLevel, fromZapLevel(toZapLevel(FatalLevel+1)))
+}
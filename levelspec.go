@@ -0,0 +1,205 @@
+package log
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync/atomic"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// LevelSpec holds the levels of named loggers, written as
+// "db=debug,http.client=warn,*=info". A name also matches the loggers
+// below it, "http" covers "http.client" unless that has its own entry.
+// The "*" entry, or a bare level, sets the level of the logger itself.
+//
+// *LevelSpec implements flag.Value.
+type LevelSpec struct {
+	levels map[string]Level
+}
+
+// ParseLevelSpec parses a comma separated list of name=level entries.
+func ParseLevelSpec(spec string) (LevelSpec, error) {
+	levels := make(map[string]Level)
+
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		name, value := "*", entry
+		if i := strings.IndexByte(entry, '='); i >= 0 {
+			name, value = strings.TrimSpace(entry[:i]), strings.TrimSpace(entry[i+1:])
+		}
+
+		if name == "" {
+			return LevelSpec{}, fmt.Errorf("invalid level spec entry %q: missing name", entry)
+		}
+
+		lvl, err := ParseLevel(value)
+		if err != nil {
+			return LevelSpec{}, fmt.Errorf("invalid level spec entry %q: %v", entry, err)
+		}
+
+		levels[name] = lvl
+	}
+
+	return LevelSpec{levels: levels}, nil
+}
+
+// Level returns the level of the logger name and whether the spec sets
+// one. The empty name stands for the logger itself.
+func (s LevelSpec) Level(name string) (Level, bool) {
+	if name == "" {
+		lvl, ok := s.levels["*"]
+		return lvl, ok
+	}
+
+	for {
+		if lvl, ok := s.levels[name]; ok {
+			return lvl, true
+		}
+
+		i := strings.LastIndexByte(name, '.')
+		if i < 0 {
+			return InfoLevel, false
+		}
+
+		name = name[:i]
+	}
+}
+
+func (s LevelSpec) String() string {
+	names := make([]string, 0, len(s.levels))
+	for name := range s.levels {
+		if name != "*" {
+			names = append(names, name)
+		}
+	}
+
+	sort.Strings(names)
+
+	if _, ok := s.levels["*"]; ok {
+		names = append(names, "*")
+	}
+
+	entries := make([]string, len(names))
+	for i, name := range names {
+		entries[i] = name + "=" + s.levels[name].String()
+	}
+
+	return strings.Join(entries, ",")
+}
+
+// withDefault returns a copy of s with the "*" entry, if any, set to lvl.
+func (s LevelSpec) withDefault(lvl Level) LevelSpec {
+	if cur, ok := s.levels["*"]; !ok || cur == lvl {
+		return s
+	}
+
+	levels := make(map[string]Level, len(s.levels))
+	for name, l := range s.levels {
+		levels[name] = l
+	}
+
+	levels["*"] = lvl
+
+	return LevelSpec{levels: levels}
+}
+
+// Set parses spec into s, see ParseLevelSpec.
+func (s *LevelSpec) Set(spec string) error {
+	parsed, err := ParseLevelSpec(spec)
+	if err != nil {
+		return err
+	}
+
+	*s = parsed
+
+	return nil
+}
+
+// componentLevels decides by the name of the logger whether an entry is
+// written. It is shared by a logger and its named children.
+type componentLevels struct {
+	base  zap.AtomicLevel
+	named atomic.Value // namedLevels
+}
+
+type namedLevels struct {
+	spec LevelSpec
+	min  zapcore.Level
+}
+
+func newComponentLevels(base zap.AtomicLevel) *componentLevels {
+	c := &componentLevels{base: base}
+	c.named.Store(namedLevels{min: zapcore.FatalLevel})
+
+	return c
+}
+
+// setSpec replaces the levels of named loggers and sets the level of the
+// logger to the "*" entry, if any.
+func (c *componentLevels) setSpec(spec LevelSpec) {
+	named := namedLevels{spec: spec, min: zapcore.FatalLevel}
+
+	for name, lvl := range spec.levels {
+		if name == "*" {
+			continue
+		}
+
+		if l := toZapLevel(lvl); l < named.min {
+			named.min = l
+		}
+	}
+
+	if lvl, ok := spec.Level(""); ok {
+		c.base.SetLevel(toZapLevel(lvl))
+	}
+
+	c.named.Store(named)
+}
+
+func (c *componentLevels) spec() LevelSpec {
+	return c.named.Load().(namedLevels).spec
+}
+
+// Enabled reports whether any logger may write at l.
+func (c *componentLevels) Enabled(l zapcore.Level) bool {
+	return c.base.Enabled(l) || l >= c.named.Load().(namedLevels).min
+}
+
+// enabled reports whether the logger name may write at l.
+func (c *componentLevels) enabled(name string, l zapcore.Level) bool {
+	if name != "" {
+		if lvl, ok := c.named.Load().(namedLevels).spec.Level(name); ok {
+			return l >= toZapLevel(lvl)
+		}
+	}
+
+	return c.base.Enabled(l)
+}
+
+// componentCore drops the entries of named loggers below their level.
+type componentCore struct {
+	zapcore.Core
+	levels *componentLevels
+}
+
+func (c *componentCore) With(fields []zapcore.Field) zapcore.Core {
+	return &componentCore{
+		Core:   c.Core.With(fields),
+		levels: c.levels,
+	}
+}
+
+func (c *componentCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if !c.levels.enabled(ent.LoggerName, ent.Level) {
+		return ce
+	}
+
+	return c.Core.Check(ent, ce)
+}
@@ -0,0 +1,116 @@
+package log
+
+import (
+	"bytes"
+	"flag"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseLevelSpec(t *testing.T) {
+	spec, err := ParseLevelSpec(" *=warn, db=debug ,http.client=error,http=info,")
+	assert.NoError(t, err)
+	assert.Equal(t, "db=debug,http=info,http.client=error,*=warn", spec.String())
+
+	for _, tt := range []struct {
+		name string
+		lvl  Level
+		ok   bool
+	}{
+		{"", WarnLevel, true},
+		{"db", DebugLevel, true},
+		{"db.conn", DebugLevel, true},
+		{"dbx", InfoLevel, false},
+		{"http", InfoLevel, true},
+		{"http.client", ErrorLevel, true},
+		{"http.client.pool", ErrorLevel, true},
+		{"http.server", InfoLevel, true},
+		{"cache", InfoLevel, false},
+	} {
+		lvl, ok := spec.Level(tt.name)
+		assert.Equal(t, tt.ok, ok, tt.name)
+		assert.Equal(t, tt.lvl, lvl, tt.name)
+	}
+
+	spec, err = ParseLevelSpec("debug")
+	assert.NoError(t, err)
+	assert.Equal(t, "*=debug", spec.String())
+
+	for _, s := range []string{"db=verbose", "=debug", "db"} {
+		_, err := ParseLevelSpec(s)
+		assert.Error(t, err, s)
+	}
+}
+
+func TestLevelSpec_Flag(t *testing.T) {
+	var spec LevelSpec
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.Var(&spec, "log-levels", "")
+
+	assert.NoError(t, fs.Parse([]string{"-log-levels", "db=debug,*=error"}))
+	assert.Equal(t, "db=debug,*=error", spec.String())
+}
+
+func TestLogger_LevelSpec(t *testing.T) {
+	var buf bytes.Buffer
+
+	spec, err := ParseLevelSpec("db=debug,http.client=warn")
+	assert.NoError(t, err)
+
+	l := New(WithLogToStdout(false), WithOutput(&buf), WithLevelSpec(spec))
+	db := l.Named("db")
+	client := l.Named("http").Named("client")
+	server := l.Named("http.server")
+
+	l.Debug("root debug")
+	db.Debug("db debug")
+	db.Named("conn").Debug("conn debug")
+	client.Info("client info")
+	client.Warn("client warn")
+	server.Debug("server debug")
+	server.Info("server info")
+
+	out := buf.String()
+	assert.NotContains(t, out, "root debug")
+	assert.Contains(t, out, `"logger":"db"`)
+	assert.Contains(t, out, "db debug")
+	assert.Contains(t, out, "conn debug")
+	assert.NotContains(t, out, "client info")
+	assert.Contains(t, out, "client warn")
+	assert.NotContains(t, out, "server debug")
+	assert.Contains(t, out, "server info")
+
+	// change the levels at runtime
+	buf.Reset()
+
+	spec, err = ParseLevelSpec("http=debug,*=error")
+	assert.NoError(t, err)
+
+	l.SetLevelSpec(spec)
+	assert.Equal(t, spec, l.LevelSpec())
+	assert.Equal(t, ErrorLevel, l.Level())
+
+	l.Warn("root warn")
+	db.Info("db info")
+	client.Debug("client debug")
+
+	out = buf.String()
+	assert.NotContains(t, out, "root warn")
+	assert.NotContains(t, out, "db info")
+	assert.Contains(t, out, "client debug")
+}
+
+func TestLogger_LevelSpecTargetLevel(t *testing.T) {
+	var buf bytes.Buffer
+
+	spec, err := ParseLevelSpec("db=error")
+	assert.NoError(t, err)
+
+	// outputs with their own level ignore the spec
+	l := New(WithLogToStdout(false), WithOutput(&buf), WithLevelSpec(spec), WithTargetLevel(OutputTarget, DebugLevel))
+	l.Named("db").Debug("db debug")
+
+	assert.Contains(t, buf.String(), "db debug")
+}
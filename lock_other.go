@@ -0,0 +1,10 @@
+//go:build !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd
+// +build !darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd
+
+package log
+
+// lockFile is a no-op where flock(2) is not available, rotation is only
+// coordinated within the process.
+func lockFile(string) (unlock func(), err error) {
+	return func() {}, nil
+}
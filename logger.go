@@ -1,12 +1,14 @@
 package log
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 
-	"github.com/natefinch/lumberjack"
+	"go.uber.org/multierr"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 )
@@ -16,10 +18,28 @@ type logfFunc func(logger *zap.SugaredLogger, format string, args ...interface{}
 type logwFunc func(logger *zap.SugaredLogger, msg string, keysAndValues ...interface{})
 
 type Logger struct {
-	base    *zap.SugaredLogger
-	writers []*lumberjack.Logger
+	base *zap.SugaredLogger
+	zap  *zap.Logger
+	name string
+
+	*loggerState
+}
+
+// loggerState is shared by a logger and the children derived from it.
+type loggerState struct {
+	level   zap.AtomicLevel
+	levels  *componentLevels
+	writers *writerSet
 	options options
 
+	verbosity int32
+
+	// enabled reports whether the logger name writes entries at a level
+	enabled func(name string, lvl zapcore.Level) bool
+
+	closeOnce sync.Once
+	closeErr  error
+
 	print  logFunc
 	printf logfFunc
 	printw logwFunc
@@ -66,6 +86,7 @@ var defaultOptions = options{
 	Format:      FormatJSON,
 	Level:       InfoLevel,
 	LogToStdout: true,
+	SplitLevel:  WarnLevel,
 	AddCaller:   false,
 	CallerSkip:  1,
 }
@@ -86,47 +107,90 @@ func newLogger(opts options) *Logger {
 	if opts.Encoder != nil {
 		encoder = opts.Encoder
 	} else {
-		var encoderCfg zapcore.EncoderConfig
+		encoder = newEncoder(opts.Format, opts.EncoderConfig())
+	}
 
-		if opts.Development {
-			encoderCfg = zap.NewDevelopmentEncoderConfig()
-			encoderCfg.EncodeTime = zapcore.RFC3339TimeEncoder
-			encoderCfg.EncodeCaller = zapcore.FullCallerEncoder
-		} else {
-			encoderCfg = zap.NewProductionEncoderConfig()
-			encoderCfg.TimeKey = "time"
-			encoderCfg.EncodeTime = zapcore.RFC3339TimeEncoder
-		}
+	// cores without their own level follow the level of the named logger
+	cores := make([]zapcore.Core, 0)
+	targetCores := make([]zapcore.Core, 0)
 
-		switch opts.Format {
-		case FormatJSON:
-			encoder = zapcore.NewJSONEncoder(encoderCfg)
-		default:
-			encoder = zapcore.NewConsoleEncoder(encoderCfg)
+	addCore := func(t Target, core zapcore.Core) {
+		if opts.Targets[t].Level != nil {
+			targetCores = append(targetCores, core)
+		} else {
+			cores = append(cores, core)
 		}
 	}
 
-	cores := make([]zapcore.Core, 0)
-	writers := make([]*lumberjack.Logger, 0)
+	writers := &writerSet{}
+	level := zap.NewAtomicLevelAt(opts.ZapLevel())
+	levels := newComponentLevels(level)
+	levels.setSpec(opts.LevelSpec)
+
+	// add stdout and stderr logs
+	if opts.SplitStreams {
+		stdoutEnabled := opts.TargetLevelEnabled(StdoutTarget, levels)
+		stderrEnabled := opts.TargetLevelEnabled(StderrTarget, levels)
 
-	// add stdout log
-	if opts.LogToStdout {
 		stdoutCore := zapcore.NewCore(
-			encoder,
-			zapcore.Lock(os.Stdout),
-			zap.LevelEnablerFunc(opts.ZapLevelEnabled),
+			opts.TargetEncoder(StdoutTarget, encoder),
+			zapcore.Lock(stdWriter{os.Stdout}),
+			zap.LevelEnablerFunc(func(l zapcore.Level) bool {
+				return !opts.SplitLevel.Enabled(fromZapLevel(l)) && stdoutEnabled(l)
+			}),
+		)
+
+		stderrCore := zapcore.NewCore(
+			opts.TargetEncoder(StderrTarget, encoder),
+			zapcore.Lock(stdWriter{os.Stderr}),
+			zap.LevelEnablerFunc(func(l zapcore.Level) bool {
+				return opts.SplitLevel.Enabled(fromZapLevel(l)) && stderrEnabled(l)
+			}),
 		)
-		cores = append(cores, stdoutCore)
+
+		addCore(StdoutTarget, stdoutCore)
+		addCore(StderrTarget, stderrCore)
+	} else {
+		if opts.LogToStdout {
+			stdoutCore := zapcore.NewCore(
+				opts.TargetEncoder(StdoutTarget, encoder),
+				zapcore.Lock(stdWriter{os.Stdout}),
+				zap.LevelEnablerFunc(opts.TargetLevelEnabled(StdoutTarget, levels)),
+			)
+			addCore(StdoutTarget, stdoutCore)
+		}
+
+		if opts.LogToStderr {
+			stderrCore := zapcore.NewCore(
+				opts.TargetEncoder(StderrTarget, encoder),
+				zapcore.Lock(stdWriter{os.Stderr}),
+				zap.LevelEnablerFunc(opts.TargetLevelEnabled(StderrTarget, levels)),
+			)
+			addCore(StderrTarget, stderrCore)
+		}
 	}
 
 	// add output core
 	if opts.Output != nil {
 		outputCore := zapcore.NewCore(
-			encoder,
+			opts.TargetEncoder(OutputTarget, encoder),
 			zapcore.Lock(zapcore.AddSync(opts.Output)),
-			zap.LevelEnablerFunc(opts.ZapLevelEnabled),
+			zap.LevelEnablerFunc(opts.TargetLevelEnabled(OutputTarget, levels)),
 		)
-		cores = append(cores, outputCore)
+		addCore(OutputTarget, outputCore)
+	}
+
+	// add slog handler core
+	if opts.SlogHandler != nil {
+		encoderCfg := opts.EncoderConfig()
+
+		slogCore := &slogCore{
+			LevelEnabler: zap.LevelEnablerFunc(opts.TargetLevelEnabled(SlogTarget, levels)),
+			handler:      opts.SlogHandler,
+			nameKey:      encoderCfg.NameKey,
+			stackKey:     encoderCfg.StacktraceKey,
+		}
+		addCore(SlogTarget, slogCore)
 	}
 
 	// parse log dirs
@@ -135,34 +199,41 @@ func newLogger(opts options) *Logger {
 			continue
 		}
 
-		for _, level := range []zapcore.Level{
-			zapcore.DebugLevel,
-			zapcore.InfoLevel,
-			zapcore.WarnLevel,
-			zapcore.ErrorLevel,
-			zapcore.DPanicLevel,
-			zapcore.PanicLevel,
-			zapcore.FatalLevel,
-		} {
-			if opts.ZapLevelEnabled(level) {
-				lvl := level
-
-				lvlWriter := newRotateWriter(
-					filepath.Join(dir, fmt.Sprint(lvl.String(), ".log")),
-					opts.RotationConfig,
-				)
-
-				lvlCore := zapcore.NewCore(
-					encoder,
-					zapcore.AddSync(lvlWriter),
-					zap.LevelEnablerFunc(func(l zapcore.Level) bool {
-						return l == lvl
-					}),
-				)
-
-				cores = append(cores, lvlCore)
-				writers = append(writers, lvlWriter)
+		// all level files in a dir share one MaxTotalSize budget
+		files := make([]string, len(fileLevels))
+		for i, level := range fileLevels {
+			files[i] = levelFile(dir, level)
+		}
+
+		enabled := opts.TargetLevelEnabled(DirTarget(dir), levels)
+		dirEncoder := opts.TargetEncoder(DirTarget(dir), encoder)
+
+		// files are only opened with their first entry, the level may be
+		// lowered at runtime
+		for i, level := range fileLevels {
+			lvl := level
+			file := files[i]
+
+			lvlWriter := &lazyWriter{
+				path: absPath(file),
+				enabled: func() bool {
+					return enabled(lvl)
+				},
+				open: func() (*writerRef, error) {
+					return writers.open(file, opts.RotationConfig, opts.rotateHooks(), files...)
+				},
 			}
+			writers.addLazy(lvlWriter)
+
+			lvlCore := zapcore.NewCore(
+				dirEncoder,
+				lvlWriter,
+				zap.LevelEnablerFunc(func(l zapcore.Level) bool {
+					return l == lvl && enabled(l)
+				}),
+			)
+
+			addCore(DirTarget(dir), lvlCore)
 		}
 	}
 
@@ -172,16 +243,16 @@ func newLogger(opts options) *Logger {
 			continue
 		}
 
-		writer := newRotateWriter(file, opts.RotationConfig)
+		// the set is not closed before the logger is returned
+		writer, _ := writers.open(file, opts.RotationConfig, opts.rotateHooks())
 
 		fileCore := zapcore.NewCore(
-			encoder,
+			opts.TargetEncoder(FileTarget(file), encoder),
 			zapcore.AddSync(writer),
-			zap.LevelEnablerFunc(opts.ZapLevelEnabled),
+			zap.LevelEnablerFunc(opts.TargetLevelEnabled(FileTarget(file), levels)),
 		)
 
-		cores = append(cores, fileCore)
-		writers = append(writers, writer)
+		addCore(FileTarget(file), fileCore)
 	}
 
 	zapOptions := []zap.Option{
@@ -193,11 +264,30 @@ func newLogger(opts options) *Logger {
 		zapOptions = append(zapOptions, zap.Development())
 	}
 
-	l := &Logger{
-		base:    zap.New(zapcore.NewTee(cores...), zapOptions...).Sugar(),
+	sharedCore := zapcore.NewTee(cores...)
+	targetCore := zapcore.NewTee(targetCores...)
+
+	core := zapcore.NewTee(targetCore, &componentCore{
+		Core:   sharedCore,
+		levels: levels,
+	})
+
+	if opts.Stacktrace != nil {
+		core = newStacktraceCore(core, *opts.Stacktrace, opts.EncoderConfig().StacktraceKey)
+	}
+
+	l := &loggerState{
+		level:   level,
+		levels:  levels,
 		writers: writers,
 		options: opts,
 
+		verbosity: int32(opts.Verbosity),
+
+		enabled: func(name string, lvl zapcore.Level) bool {
+			return targetCore.Enabled(lvl) || (levels.enabled(name, lvl) && sharedCore.Enabled(lvl))
+		},
+
 		debug:   (*zap.SugaredLogger).Debug,
 		debugf:  (*zap.SugaredLogger).Debugf,
 		debugw:  (*zap.SugaredLogger).Debugw,
@@ -231,30 +321,112 @@ func newLogger(opts options) *Logger {
 		l.printw = (*zap.SugaredLogger).Infow
 	}
 
-	return l
+	return newChild(zap.New(core, zapOptions...).Sugar(), "", l)
 }
 
-func newRotateWriter(filename string, config RotationConfig) *lumberjack.Logger {
-	return &lumberjack.Logger{
-		Filename:   filename,
-		MaxSize:    config.MaxSize,
-		MaxAge:     config.MaxAge,
-		MaxBackups: config.MaxBackups,
-		LocalTime:  config.LocalTime,
-		Compress:   config.Compress,
+func newChild(base *zap.SugaredLogger, name string, state *loggerState) *Logger {
+	return &Logger{
+		base:        base,
+		zap:         base.Desugar(),
+		name:        name,
+		loggerState: state,
 	}
 }
 
+// WithOptions returns a new logger with opt applied to the options of l,
+// starting from the level, level spec and verbosity l has at the moment.
 func (l *Logger) WithOptions(opt ...Option) *Logger {
 	opts := l.options.Clone()
+	opts.LevelSpec = l.LevelSpec()
+	opts.Verbosity = l.Verbosity()
+
+	// development loggers run at DebugLevel, which is not their configured
+	// level
+	if !opts.Development {
+		opts.Level = l.Level()
+		opts.LevelSpec = opts.LevelSpec.withDefault(opts.Level)
+	}
+
+	carried := opts
 
 	for _, o := range opt {
 		o.apply(&opts)
 	}
 
+	// a level given to WithOptions is not overridden by the carried "*"
+	if opts.Level != carried.Level && opts.LevelSpec.String() == carried.LevelSpec.String() {
+		opts.LevelSpec = opts.LevelSpec.withDefault(opts.Level)
+	}
+
 	return newLogger(opts)
 }
 
+// SetLevel changes the minimum level of the logger at runtime. Outputs with
+// their own level set by WithTargetLevel are not affected.
+func (l *Logger) SetLevel(lvl Level) {
+	l.level.SetLevel(toZapLevel(lvl))
+}
+
+// SetLevelSpec changes the levels of the named loggers derived from l at
+// runtime, see LevelSpec.
+func (l *Logger) SetLevelSpec(spec LevelSpec) {
+	l.levels.setSpec(spec)
+}
+
+// LevelSpec returns the levels of the named loggers as last set.
+func (l *Logger) LevelSpec() LevelSpec {
+	return l.levels.spec()
+}
+
+// Named returns a child logger with name appended to the name of l,
+// separated by a period. The child shares the outputs and levels of l.
+func (l *Logger) Named(name string) *Logger {
+	if name == "" {
+		return l
+	}
+
+	full := name
+	if l.name != "" {
+		full = l.name + "." + name
+	}
+
+	return newChild(l.base.Named(name), full, l.loggerState)
+}
+
+// With returns a child logger that adds keysAndValues to every entry, see
+// zap.SugaredLogger.With. The child shares the outputs and levels of l.
+func (l *Logger) With(keysAndValues ...interface{}) *Logger {
+	return newChild(l.base.With(keysAndValues...), l.name, l.loggerState)
+}
+
+// WithCallerSkip returns a child logger that skips skip more callers when
+// reporting the caller, for wrappers around the logger. The child shares
+// the outputs and levels of l.
+func (l *Logger) WithCallerSkip(skip int) *Logger {
+	return newChild(l.zap.WithOptions(zap.AddCallerSkip(skip)).Sugar(), l.name, l.loggerState)
+}
+
+// Enabled reports whether l writes entries at lvl to any output.
+func (l *Logger) Enabled(lvl Level) bool {
+	return l.enabled(l.name, toZapLevel(lvl))
+}
+
+// Zap returns the underlying zap.Logger, which reports the caller of its
+// own methods. It shares the outputs and levels of l.
+func (l *Logger) Zap() *zap.Logger {
+	return l.zap.WithOptions(zap.AddCallerSkip(-1))
+}
+
+// Desugar is the same as Zap, named after zap.SugaredLogger.Desugar.
+func (l *Logger) Desugar() *zap.Logger {
+	return l.Zap()
+}
+
+// Level returns the minimum level of the logger.
+func (l *Logger) Level() Level {
+	return fromZapLevel(l.level.Level())
+}
+
 func (l *Logger) Print(args ...interface{}) {
 	l.print(l.base, args...)
 }
@@ -383,16 +555,265 @@ func (l *Logger) Fatalw(msg string, keysAndValues ...interface{}) {
 	l.fatalw(l.base, msg, keysAndValues...)
 }
 
+// DebugFields logs a message with typed fields, without the allocations
+// of the sugared methods. Nothing is allocated at disabled levels.
+func (l *Logger) DebugFields(msg string, fields ...Field) {
+	if ce := l.zap.Check(zapcore.DebugLevel, msg); ce != nil {
+		ce.Write(copyFields(fields)...)
+	}
+}
+
+func (l *Logger) InfoFields(msg string, fields ...Field) {
+	if ce := l.zap.Check(zapcore.InfoLevel, msg); ce != nil {
+		ce.Write(copyFields(fields)...)
+	}
+}
+
+func (l *Logger) WarnFields(msg string, fields ...Field) {
+	if ce := l.zap.Check(zapcore.WarnLevel, msg); ce != nil {
+		ce.Write(copyFields(fields)...)
+	}
+}
+
+func (l *Logger) ErrorFields(msg string, fields ...Field) {
+	if ce := l.zap.Check(zapcore.ErrorLevel, msg); ce != nil {
+		ce.Write(copyFields(fields)...)
+	}
+}
+
+func (l *Logger) DPanicFields(msg string, fields ...Field) {
+	if ce := l.zap.Check(zapcore.DPanicLevel, msg); ce != nil {
+		ce.Write(copyFields(fields)...)
+	}
+}
+
+func (l *Logger) PanicFields(msg string, fields ...Field) {
+	if ce := l.zap.Check(zapcore.PanicLevel, msg); ce != nil {
+		ce.Write(copyFields(fields)...)
+	}
+}
+
+func (l *Logger) FatalFields(msg string, fields ...Field) {
+	if ce := l.zap.Check(zapcore.FatalLevel, msg); ce != nil {
+		ce.Write(copyFields(fields)...)
+	}
+}
+
+// DebugEvent starts an entry built by chained calls, see Event. It is nil
+// if the level is disabled.
+func (l *Logger) DebugEvent() *Event {
+	return newEvent(l.zap.Check(zapcore.DebugLevel, ""))
+}
+
+func (l *Logger) InfoEvent() *Event {
+	return newEvent(l.zap.Check(zapcore.InfoLevel, ""))
+}
+
+func (l *Logger) WarnEvent() *Event {
+	return newEvent(l.zap.Check(zapcore.WarnLevel, ""))
+}
+
+func (l *Logger) ErrorEvent() *Event {
+	return newEvent(l.zap.Check(zapcore.ErrorLevel, ""))
+}
+
+func (l *Logger) DPanicEvent() *Event {
+	return newEvent(l.zap.Check(zapcore.DPanicLevel, ""))
+}
+
+func (l *Logger) PanicEvent() *Event {
+	return newEvent(l.zap.Check(zapcore.PanicLevel, ""))
+}
+
+func (l *Logger) FatalEvent() *Event {
+	return newEvent(l.zap.Check(zapcore.FatalLevel, ""))
+}
+
+// Logw logs a message with some additional context at lvl, which may also
+// be one of the verbose debug levels below DebugLevel.
+func (l *Logger) Logw(lvl Level, msg string, keysAndValues ...interface{}) {
+	if ce := l.zap.Check(toZapLevel(lvl), msg); ce != nil {
+		ce.Write(keysAndValuesFields(keysAndValues)...)
+	}
+}
+
+// DebugCtx logs a message with the key-value pairs extracted from ctx and
+// keysAndValues, see RegisterContextExtractor.
+func (l *Logger) DebugCtx(ctx context.Context, msg string, keysAndValues ...interface{}) {
+	// the extractors only run for entries that are logged
+	if ce := l.zap.Check(zapcore.DebugLevel, msg); ce != nil {
+		ce.Write(keysAndValuesFields(contextKeysAndValues(ctx, keysAndValues))...)
+	}
+}
+
+func (l *Logger) InfoCtx(ctx context.Context, msg string, keysAndValues ...interface{}) {
+	if ce := l.zap.Check(zapcore.InfoLevel, msg); ce != nil {
+		ce.Write(keysAndValuesFields(contextKeysAndValues(ctx, keysAndValues))...)
+	}
+}
+
+func (l *Logger) WarnCtx(ctx context.Context, msg string, keysAndValues ...interface{}) {
+	if ce := l.zap.Check(zapcore.WarnLevel, msg); ce != nil {
+		ce.Write(keysAndValuesFields(contextKeysAndValues(ctx, keysAndValues))...)
+	}
+}
+
+func (l *Logger) ErrorCtx(ctx context.Context, msg string, keysAndValues ...interface{}) {
+	if ce := l.zap.Check(zapcore.ErrorLevel, msg); ce != nil {
+		ce.Write(keysAndValuesFields(contextKeysAndValues(ctx, keysAndValues))...)
+	}
+}
+
+func (l *Logger) DPanicCtx(ctx context.Context, msg string, keysAndValues ...interface{}) {
+	if ce := l.zap.Check(zapcore.DPanicLevel, msg); ce != nil {
+		ce.Write(keysAndValuesFields(contextKeysAndValues(ctx, keysAndValues))...)
+	}
+}
+
+func (l *Logger) PanicCtx(ctx context.Context, msg string, keysAndValues ...interface{}) {
+	if ce := l.zap.Check(zapcore.PanicLevel, msg); ce != nil {
+		ce.Write(keysAndValuesFields(contextKeysAndValues(ctx, keysAndValues))...)
+	}
+}
+
+func (l *Logger) FatalCtx(ctx context.Context, msg string, keysAndValues ...interface{}) {
+	if ce := l.zap.Check(zapcore.FatalLevel, msg); ce != nil {
+		ce.Write(keysAndValuesFields(contextKeysAndValues(ctx, keysAndValues))...)
+	}
+}
+
+// Rotate rotates every log file of the logger. It attempts all of them and
+// returns the combined errors of those that failed.
 func (l *Logger) Rotate() error {
-	for _, w := range l.writers {
-		if err := w.Rotate(); err != nil {
+	err := l.openLevelFiles()
+
+	for _, w := range l.writers.list() {
+		if errRotate := w.Rotate(); errRotate != nil {
+			err = multierr.Append(err, fmt.Errorf("rotate %s: %w", w.path, errRotate))
+		}
+	}
+
+	return err
+}
+
+// Reopen closes and reopens every log file of the logger without renaming
+// it, for use after the files have been moved by an external tool such as
+// logrotate. It returns the combined errors of those that failed.
+func (l *Logger) Reopen() error {
+	err := l.openLevelFiles()
+
+	for _, w := range l.writers.list() {
+		if errReopen := w.Reopen(); errReopen != nil {
+			err = multierr.Append(err, fmt.Errorf("reopen %s: %w", w.path, errReopen))
+		}
+	}
+
+	return err
+}
+
+// openLevelFiles opens the level files of LogDirs that have not been
+// written yet but exist from an earlier run, if their level is enabled, so
+// that Rotate and Reopen cover them.
+func (l *Logger) openLevelFiles() error {
+	var err error
+
+	for _, w := range l.writers.lazyList() {
+		if !w.enabled() {
+			continue
+		}
+
+		if _, errStat := os.Stat(w.path); errStat != nil {
+			continue
+		}
+
+		if _, errOpen := w.writer(); errOpen != nil {
+			err = multierr.Append(err, fmt.Errorf("open %s: %w", w.path, errOpen))
+		}
+	}
+
+	return err
+}
+
+// RotateFile rotates a single log file of the logger, path being one of
+// its LogFiles or a level file in one of its LogDirs.
+func (l *Logger) RotateFile(path string) error {
+	path = absPath(path)
+
+	// level files are only opened with their first entry
+	if w := l.writers.lookupLazy(path); w != nil {
+		ref, err := w.writer()
+		if err != nil {
 			return err
 		}
+
+		return ref.Rotate()
+	}
+
+	for _, w := range l.writers.list() {
+		if w.path == path {
+			return w.Rotate()
+		}
 	}
 
-	return nil
+	return fmt.Errorf("log: not a log file of this logger: %s", path)
+}
+
+// Sync flushes any buffered log entries.
+func (l *Logger) Sync() error {
+	return l.base.Sync()
+}
+
+// Close flushes the logger and closes its log files. Entries logged after
+// Close are quietly dropped by the files, which are never reopened, but
+// still written to stdout, stderr and Output.
+func (l *Logger) Close() error {
+	l.closeOnce.Do(func() {
+		l.closeErr = multierr.Append(l.Sync(), l.writers.close())
+	})
+
+	return l.closeErr
+}
+
+// fileLevels are the levels with a file of their own in each of LogDirs.
+var fileLevels = []zapcore.Level{
+	zapcore.DebugLevel,
+	zapcore.InfoLevel,
+	zapcore.WarnLevel,
+	zapcore.ErrorLevel,
+	zapcore.DPanicLevel,
+	zapcore.PanicLevel,
+	zapcore.FatalLevel,
+}
+
+func levelFile(dir string, level zapcore.Level) string {
+	return filepath.Join(dir, level.String()+".log")
 }
 
 func sprintln(args ...interface{}) string {
 	return strings.TrimSuffix(fmt.Sprintln(args...), "\n")
 }
+
+// keysAndValuesFields turns loosely typed key-value pairs into fields like
+// zap.SugaredLogger does, dropping malformed pairs.
+func keysAndValuesFields(keysAndValues []interface{}) []zapcore.Field {
+	var fields []zapcore.Field
+
+	for i := 0; i < len(keysAndValues); i++ {
+		if f, ok := keysAndValues[i].(zapcore.Field); ok {
+			fields = append(fields, f)
+			continue
+		}
+
+		if i+1 == len(keysAndValues) {
+			break
+		}
+
+		if key, ok := keysAndValues[i].(string); ok {
+			fields = append(fields, zap.Any(key, keysAndValues[i+1]))
+		}
+
+		i++
+	}
+
+	return fields
+}
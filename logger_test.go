@@ -1,9 +1,16 @@
 package log
 
 import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"go.uber.org/multierr"
+	"go.uber.org/zap"
 )
 
 func TestLogger_WithOptions(t *testing.T) {
@@ -84,10 +91,509 @@ func Test_LoggerPanic(t *testing.T) {
 }
 
 func TestLogger_LogDir(t *testing.T) {
-	l := New(WithLogDirs("log"))
+	dir := tempDir(t)
+
+	l := New(WithLogDirs(dir))
+	defer l.Close()
 
 	l.Print("info")
 	l.Print("info")
 	l.Infoln("info, fff")
 	l.Warn("debug")
+
+	assert.Equal(t, []string{"info.log", "warn.log"}, readDir(t, dir))
+}
+
+func tempDir(t *testing.T) string {
+	t.Helper()
+
+	dir, err := ioutil.TempDir("", "log-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		_ = os.RemoveAll(dir)
+	})
+
+	return dir
+}
+
+func TestLogger_Close(t *testing.T) {
+	dir := tempDir(t)
+	file := filepath.Join(dir, "test.log")
+
+	l := New(WithLogToStdout(false), WithLogFiles(file))
+
+	l.Info("before close")
+	assert.NoError(t, l.Sync())
+	assert.NoError(t, l.Close())
+	assert.NoError(t, l.Close())
+
+	content, err := ioutil.ReadFile(file)
+	assert.NoError(t, err)
+	assert.Contains(t, string(content), "before close")
+
+	assert.NoError(t, os.Remove(file))
+
+	l.Info("after close")
+
+	_, err = os.Stat(file)
+	assert.True(t, os.IsNotExist(err), "closed logger must not reopen its files")
+}
+
+func TestLogger_CloseQuiet(t *testing.T) {
+	dir := tempDir(t)
+
+	stderr, err := os.Create(filepath.Join(dir, "stderr"))
+	assert.NoError(t, err)
+	defer stderr.Close()
+
+	// zap keeps the stderr of the time the logger is built for its errors
+	orig := os.Stderr
+	os.Stderr = stderr
+	defer func() { os.Stderr = orig }()
+
+	var buf bytes.Buffer
+
+	l := New(WithLogToStdout(false), WithOutput(&buf),
+		WithLogFiles(filepath.Join(dir, "test.log")), WithLogDirs(filepath.Join(dir, "levels")))
+	assert.NoError(t, l.Close())
+
+	l.Info("after close")
+	l.Warn("after close")
+
+	assert.Contains(t, buf.String(), "after close")
+	assert.Empty(t, readFile(t, stderr.Name()))
+}
+
+func TestLogger_OnRotate(t *testing.T) {
+	dir := tempDir(t)
+	file := filepath.Join(dir, "test.log")
+
+	events := make(chan RotateEvent, 1)
+
+	l := New(WithLogToStdout(false), WithLogFiles(file), OnRotate(func(e RotateEvent) {
+		events <- e
+	}))
+	defer l.Close()
+
+	l.Info("rotate me")
+	assert.NoError(t, l.Rotate())
+
+	select {
+	case e := <-events:
+		assert.Equal(t, file, e.Filename)
+		assert.Contains(t, readFile(t, e.Backup), "rotate me")
+		assert.Empty(t, e.Compressed)
+	case <-time.After(5 * time.Second):
+		t.Fatal("OnRotate not called")
+	}
+}
+
+func TestLogger_OnRotateLogsDuringClose(t *testing.T) {
+	dir := tempDir(t)
+
+	closing := make(chan struct{})
+
+	var l *Logger
+	l = New(WithLogToStdout(false), WithLogDirs(dir), OnRotate(func(e RotateEvent) {
+		<-closing
+		time.Sleep(10 * time.Millisecond)
+
+		// the warn file is opened lazily
+		l.Infow("rotated", "backup", e.Backup)
+		l.Warnw("rotated", "backup", e.Backup)
+	}))
+
+	l.Info("rotate me")
+	assert.NoError(t, l.Rotate())
+
+	done := make(chan error, 1)
+
+	go func() {
+		done <- l.Close()
+	}()
+
+	close(closing)
+
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Close blocked by OnRotate")
+	}
+}
+
+func TestLogger_OnRotateWithOptions(t *testing.T) {
+	file := filepath.Join(tempDir(t), "test.log")
+
+	done := make(chan struct{})
+
+	var l *Logger
+	l = New(WithLogToStdout(false), WithLogFiles(file), OnRotate(func(RotateEvent) {
+		// opens the same file again
+		n := l.WithOptions(RotationConfig{MaxBackups: 1})
+		n.Info("from hook")
+		_ = n.Close()
+
+		close(done)
+	}))
+
+	l.Info("rotate me")
+	assert.NoError(t, l.Rotate())
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("OnRotate blocked by WithOptions")
+	}
+
+	assert.NoError(t, l.Close())
+	assert.Contains(t, readFile(t, file), "from hook")
+}
+
+func TestLogger_RotateAll(t *testing.T) {
+	dir := tempDir(t)
+	broken := filepath.Join(dir, "broken", "a.log")
+	working := filepath.Join(dir, "working", "b.log")
+
+	l := New(WithLogToStdout(false), WithLogFiles(broken, working))
+	defer l.Close()
+
+	l.Info("line")
+
+	// replace the directory with a file, so that it cannot be recreated
+	assert.NoError(t, os.RemoveAll(filepath.Dir(broken)))
+	assert.NoError(t, ioutil.WriteFile(filepath.Dir(broken), nil, 0600))
+
+	err := l.Rotate()
+	if assert.Error(t, err) {
+		assert.Len(t, multierr.Errors(err), 1)
+		assert.Contains(t, err.Error(), broken)
+	}
+
+	assert.Len(t, readDir(t, filepath.Dir(working)), 2)
+}
+
+func TestLogger_RotateFile(t *testing.T) {
+	dir := tempDir(t)
+	a := filepath.Join(dir, "a.log")
+	b := filepath.Join(dir, "b.log")
+
+	l := New(WithLogToStdout(false), WithLogFiles(a, b))
+	defer l.Close()
+
+	l.Info("line")
+
+	assert.NoError(t, l.RotateFile(a))
+	assert.Len(t, readDir(t, dir), 3)
+	assert.Contains(t, readFile(t, b), "line")
+
+	assert.Error(t, l.RotateFile(filepath.Join(dir, "unknown.log")))
+}
+
+func TestLogger_RotateFileLogDir(t *testing.T) {
+	dir := tempDir(t)
+
+	warn := filepath.Join(dir, "warn.log")
+
+	// left by an earlier run
+	assert.NoError(t, ioutil.WriteFile(warn, []byte("earlier\n"), 0600))
+
+	l := New(WithLogToStdout(false), WithLogDirs(dir))
+	defer l.Close()
+
+	l.Info("line")
+
+	assert.NoError(t, l.RotateFile(filepath.Join(dir, "info.log")))
+	assert.Len(t, readDir(t, dir), 3)
+
+	// not written by this logger yet
+	assert.NoError(t, l.RotateFile(warn))
+
+	files := readDir(t, dir)
+	if assert.Len(t, files, 4) {
+		assert.Equal(t, "earlier\n", readFile(t, filepath.Join(dir, files[2])))
+	}
+
+	assert.Empty(t, readFile(t, warn))
+}
+
+func TestLogger_RotateLogDir(t *testing.T) {
+	dir := tempDir(t)
+
+	info := filepath.Join(dir, "info.log")
+	debug := filepath.Join(dir, "debug.log")
+
+	// left by an earlier run
+	assert.NoError(t, ioutil.WriteFile(info, []byte("earlier\n"), 0600))
+	assert.NoError(t, ioutil.WriteFile(debug, []byte("earlier\n"), 0600))
+
+	l := New(WithLogToStdout(false), WithLogDirs(dir))
+	defer l.Close()
+
+	assert.NoError(t, l.Rotate())
+
+	// debug is not enabled, its file is left alone
+	files := readDir(t, dir)
+	if assert.Len(t, files, 3) {
+		assert.Equal(t, "earlier\n", readFile(t, debug))
+		assert.Empty(t, readFile(t, info))
+	}
+
+	assert.NoError(t, l.Reopen())
+	assert.Len(t, readDir(t, dir), 3)
+}
+
+func TestLogger_TargetLevel(t *testing.T) {
+	dir := tempDir(t)
+	file := filepath.Join(dir, "debug.log")
+	logDir := filepath.Join(dir, "levels")
+
+	var buf bytes.Buffer
+
+	l := New(
+		WithLogToStdout(false),
+		WithOutput(&buf),
+		WithLogFiles(file),
+		WithLogDirs(logDir),
+		WithTargetLevel(OutputTarget, WarnLevel),
+		WithTargetLevel(FileTarget(file), DebugLevel),
+		WithTargetLevel(DirTarget(logDir+"/"), ErrorLevel),
+	)
+	defer l.Close()
+
+	l.Debug("debug entry")
+	l.Info("info entry")
+	l.Warn("warn entry")
+	l.Error("error entry")
+
+	assert.NotContains(t, buf.String(), "info entry")
+	assert.Contains(t, buf.String(), "warn entry")
+
+	content := readFile(t, file)
+	assert.Contains(t, content, "debug entry")
+	assert.Contains(t, content, "info entry")
+
+	assert.Equal(t, []string{"error.log"}, readDir(t, logDir))
+}
+
+func TestLogger_TargetFormat(t *testing.T) {
+	dir := tempDir(t)
+	file := filepath.Join(dir, "app.log")
+	logDir := filepath.Join(dir, "levels")
+
+	var buf bytes.Buffer
+
+	encoderCfg := zap.NewProductionEncoderConfig()
+	encoderCfg.MessageKey = "message"
+
+	l := New(
+		WithLogToStdout(false),
+		WithFormat(FormatJSON),
+		WithOutput(&buf),
+		WithLogFiles(file),
+		WithLogDirs(logDir),
+		WithTargetFormat(OutputTarget, FormatConsole),
+		WithTargetEncoderConfig(FileTarget(file), encoderCfg),
+	)
+	defer l.Close()
+
+	l.Infow("entry", "key", "value")
+
+	assert.Contains(t, buf.String(), "\tinfo\tentry\t{\"key\": \"value\"}")
+
+	assert.Contains(t, readFile(t, file), `"message":"entry"`)
+	assert.Contains(t, readFile(t, filepath.Join(logDir, "info.log")), `"msg":"entry"`)
+}
+
+// captureStd redirects os.Stdout and os.Stderr to files while fn runs.
+func captureStd(t *testing.T, fn func()) (stdout, stderr string) {
+	t.Helper()
+
+	dir := tempDir(t)
+
+	out, err := os.Create(filepath.Join(dir, "stdout"))
+	assert.NoError(t, err)
+	defer out.Close()
+
+	errOut, err := os.Create(filepath.Join(dir, "stderr"))
+	assert.NoError(t, err)
+	defer errOut.Close()
+
+	origOut, origErr := os.Stdout, os.Stderr
+	os.Stdout, os.Stderr = out, errOut
+
+	defer func() {
+		os.Stdout, os.Stderr = origOut, origErr
+	}()
+
+	fn()
+
+	return readFile(t, out.Name()), readFile(t, errOut.Name())
+}
+
+func TestLogger_LogToStderr(t *testing.T) {
+	stdout, stderr := captureStd(t, func() {
+		l := New(WithLogToStdout(false), LogToStderr())
+		l.Info("to stderr")
+	})
+
+	assert.Empty(t, stdout)
+	assert.Contains(t, stderr, "to stderr")
+}
+
+func TestLogger_SplitStreams(t *testing.T) {
+	stdout, stderr := captureStd(t, func() {
+		l := New(SplitStreams(), WithLevel(DebugLevel))
+		l.Debug("debug entry")
+		l.Info("info entry")
+		l.Warn("warn entry")
+		l.Error("error entry")
+
+		n := l.WithOptions(WithSplitLevel(ErrorLevel), WithTargetLevel(StdoutTarget, InfoLevel))
+		n.Debug("debug split")
+		n.Warn("warn split")
+		n.Error("error split")
+	})
+
+	assert.Contains(t, stdout, "debug entry")
+	assert.Contains(t, stdout, "info entry")
+	assert.Contains(t, stderr, "warn entry")
+	assert.Contains(t, stderr, "error entry")
+	assert.NotContains(t, stdout, "warn entry")
+	assert.NotContains(t, stderr, "info entry")
+
+	assert.NotContains(t, stdout, "debug split")
+	assert.Contains(t, stdout, "warn split")
+	assert.Contains(t, stderr, "error split")
+	assert.NotContains(t, stderr, "warn split")
+}
+
+func TestLogger_SetLevel(t *testing.T) {
+	dir := tempDir(t)
+
+	var buf bytes.Buffer
+
+	l := New(WithLogToStdout(false), WithOutput(&buf), WithLogDirs(dir))
+	defer l.Close()
+
+	assert.Equal(t, InfoLevel, l.Level())
+
+	l.Debug("hidden")
+	l.Info("shown")
+	assert.Equal(t, []string{"info.log"}, readDir(t, dir))
+
+	l.SetLevel(DebugLevel)
+	assert.Equal(t, DebugLevel, l.Level())
+
+	l.Debug("debug entry")
+	assert.Equal(t, []string{"debug.log", "info.log"}, readDir(t, dir))
+	assert.Contains(t, readFile(t, filepath.Join(dir, "debug.log")), "debug entry")
+
+	l.SetLevel(ErrorLevel)
+	l.Warn("dropped")
+
+	assert.NotContains(t, buf.String(), "hidden")
+	assert.NotContains(t, buf.String(), "dropped")
+	assert.Contains(t, buf.String(), "debug entry")
+
+	// only files that have been written are rotated
+	assert.NoError(t, l.Rotate())
+	assert.Len(t, readDir(t, dir), 4)
+}
+
+func TestLogger_WithOptionsKeepsLevel(t *testing.T) {
+	spec, err := ParseLevelSpec("db=warn,*=info")
+	assert.NoError(t, err)
+
+	l := New(WithLogToStdout(false), WithLevelSpec(spec))
+	l.SetLevel(DebugLevel)
+	l.SetVerbosity(2)
+
+	n := l.WithOptions(AddCaller())
+	assert.Equal(t, DebugLevel, n.Level())
+	assert.Equal(t, 2, n.Verbosity())
+	assert.Equal(t, "db=warn,*=debug", n.LevelSpec().String())
+
+	// options given to WithOptions still win
+	n = l.WithOptions(WithVerbosity(1))
+	assert.Equal(t, 1, n.Verbosity())
+
+	n = l.WithOptions(WithLevel(ErrorLevel))
+	assert.Equal(t, ErrorLevel, n.Level())
+	assert.Equal(t, "db=warn,*=error", n.LevelSpec().String())
+
+	d := New(Development(), WithLogToStdout(false))
+	assert.Equal(t, InfoLevel, d.WithOptions(WithDevelopment(false)).Level())
+}
+
+func TestLogger_SetLevelDevelopment(t *testing.T) {
+	var buf bytes.Buffer
+
+	l := New(Development(), WithLogToStdout(false), WithOutput(&buf))
+	assert.Equal(t, DebugLevel, l.Level())
+
+	l.SetLevel(WarnLevel)
+	l.Info("dropped")
+	assert.Empty(t, buf.String())
+}
+
+func TestLogger_WithNamed(t *testing.T) {
+	dir := tempDir(t)
+	file := filepath.Join(dir, "a.log")
+
+	l := New(WithLogToStdout(false), WithLogFiles(file))
+	defer l.Close()
+
+	child := l.Named("db").With("shard", 3)
+	child.Infow("query", "rows", 1)
+	l.Info("parent")
+
+	content := readFile(t, file)
+	assert.Contains(t, content, `"logger":"db","msg":"query","shard":3,"rows":1`)
+	assert.Contains(t, content, `"msg":"parent"}`)
+
+	// the child rotates the files of the parent
+	assert.NoError(t, child.Rotate())
+	assert.Len(t, readDir(t, dir), 2)
+
+	l.Info("after rotate")
+	child.Info("child after rotate")
+
+	content = readFile(t, file)
+	assert.Contains(t, content, "after rotate")
+	assert.Contains(t, content, "child after rotate")
+
+	// closing the parent closes the shared files
+	assert.NoError(t, l.Close())
+	assert.NoError(t, child.Close())
+	child.Info("dropped")
+	assert.NotContains(t, readFile(t, file), "dropped")
+}
+
+func TestLogger_LogwEnabled(t *testing.T) {
+	var buf bytes.Buffer
+
+	spec, err := ParseLevelSpec("db=debug")
+	assert.NoError(t, err)
+
+	l := New(WithLogToStdout(false), WithOutput(&buf), WithLevelSpec(spec))
+
+	assert.False(t, l.Enabled(DebugLevel))
+	assert.True(t, l.Named("db").Enabled(DebugLevel))
+	assert.False(t, l.Named("db").Enabled(DebugLevel-1))
+
+	l.Logw(WarnLevel, "warn", "k", 1)
+	l.Logw(DebugLevel-1, "dropped")
+	assert.Contains(t, buf.String(), `"level":"warn"`)
+	assert.Contains(t, buf.String(), `"msg":"warn","k":1`)
+	assert.NotContains(t, buf.String(), "dropped")
+
+	l.SetLevel(DebugLevel - 1)
+	assert.True(t, l.Enabled(DebugLevel-1))
+
+	l.Logw(DebugLevel-1, "verbose")
+	assert.Contains(t, buf.String(), `"msg":"verbose"`)
 }
@@ -0,0 +1,64 @@
+// Package logrsink implements logr.LogSink on top of go.kuoruan.net/log,
+// e.g. for Kubernetes controller-runtime.
+package logrsink
+
+import (
+	"github.com/go-logr/logr"
+	"go.uber.org/zap"
+
+	"go.kuoruan.net/log"
+)
+
+type sink struct {
+	l *log.Logger
+}
+
+var (
+	_ logr.LogSink          = (*sink)(nil)
+	_ logr.CallDepthLogSink = (*sink)(nil)
+)
+
+// New returns a logr.Logger that writes to l, see NewSink.
+func New(l *log.Logger) logr.Logger {
+	return logr.New(NewSink(l))
+}
+
+// NewSink returns a logr.LogSink that writes to l. V-level v is the Level
+// InfoLevel-v: V(0) is InfoLevel, V(1) DebugLevel and the higher V-levels
+// are the verbose debug levels below it. Errors are ErrorLevel.
+func NewSink(l *log.Logger) logr.LogSink {
+	// skip the method of the sink
+	return &sink{l: l.WithCallerSkip(1)}
+}
+
+func (s *sink) Init(info logr.RuntimeInfo) {
+	s.l = s.l.WithCallerSkip(info.CallDepth)
+}
+
+func (s *sink) Enabled(level int) bool {
+	return s.l.Enabled(vLevel(level))
+}
+
+func (s *sink) Info(level int, msg string, keysAndValues ...interface{}) {
+	s.l.Logw(vLevel(level), msg, keysAndValues...)
+}
+
+func (s *sink) Error(err error, msg string, keysAndValues ...interface{}) {
+	s.l.Errorw(msg, append([]interface{}{zap.Error(err)}, keysAndValues...)...)
+}
+
+func (s *sink) WithValues(keysAndValues ...interface{}) logr.LogSink {
+	return &sink{l: s.l.With(keysAndValues...)}
+}
+
+func (s *sink) WithName(name string) logr.LogSink {
+	return &sink{l: s.l.Named(name)}
+}
+
+func (s *sink) WithCallDepth(depth int) logr.LogSink {
+	return &sink{l: s.l.WithCallerSkip(depth)}
+}
+
+func vLevel(level int) log.Level {
+	return log.InfoLevel - log.Level(level)
+}
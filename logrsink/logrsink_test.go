@@ -0,0 +1,41 @@
+package logrsink
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"go.kuoruan.net/log"
+)
+
+func TestNew(t *testing.T) {
+	var buf bytes.Buffer
+
+	l := log.New(log.WithLogToStdout(false), log.WithOutput(&buf), log.AddCaller(), log.WithLevel(log.DebugLevel))
+	logger := New(l).WithName("controller").WithValues("kind", "Pod")
+
+	logger.Info("reconciled", "name", "web")
+	assert.Contains(t, buf.String(), `"level":"info","time":`)
+	assert.Contains(t, buf.String(), `/logrsink_test.go:`)
+	assert.Contains(t, buf.String(), `"logger":"controller"`)
+	assert.Contains(t, buf.String(), `"msg":"reconciled","kind":"Pod","name":"web"`)
+
+	buf.Reset()
+	logger.V(1).Info("debug")
+	logger.V(2).Info("dropped")
+	assert.Contains(t, buf.String(), `"level":"debug"`)
+	assert.NotContains(t, buf.String(), "dropped")
+	assert.True(t, logger.V(1).Enabled())
+	assert.False(t, logger.V(2).Enabled())
+
+	l.SetLevel(log.Level(-2))
+	assert.True(t, logger.V(2).Enabled())
+
+	buf.Reset()
+	logger.Error(errors.New("boom"), "failed")
+	assert.Contains(t, buf.String(), `"level":"error"`)
+	assert.Contains(t, buf.String(), `/logrsink_test.go:`)
+	assert.Contains(t, buf.String(), `"msg":"failed","kind":"Pod","error":"boom"`)
+}
@@ -2,16 +2,41 @@ package log
 
 import (
 	"io"
+	"log/slog"
 
+	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 )
 
+// RotationConfig sets how log files are rotated. Loggers writing to the
+// same file share its rotation, a logger that opens the file with another
+// config, e.g. through WithOptions or SetOptions, changes it for all of
+// them.
 type RotationConfig struct {
 	MaxSize    int  `json:"maxSize"`    // megabytes
 	MaxAge     int  `json:"maxAge"`     // days
 	MaxBackups int  `json:"maxBackups"` // count
 	LocalTime  bool `json:"localTime"`
 	Compress   bool `json:"compress"`
+
+	// MaxTotalSize caps the total size in megabytes of the backups of each
+	// file, and of all level files in each directory of LogDirs. The oldest
+	// backups are removed first.
+	MaxTotalSize int `json:"maxTotalSize"`
+
+	// MaxBytes and MaxTotalBytes take precedence over MaxSize and
+	// MaxTotalSize, for limits that are not whole megabytes.
+	MaxBytes      int64 `json:"maxBytes"`
+	MaxTotalBytes int64 `json:"maxTotalBytes"`
+
+	// Compression names the Compressor used if Compress is set, "gzip" by
+	// default. See RegisterCompressor.
+	Compression string `json:"compression"`
+
+	// Schedule cuts files at wall-clock boundaries in addition to MaxSize,
+	// see ParseSchedule for the accepted formats. Boundaries are computed
+	// in local time if LocalTime is set, UTC otherwise.
+	Schedule string `json:"schedule"`
 }
 
 func (c RotationConfig) apply(o *options) {
@@ -27,41 +52,104 @@ func (c RotationConfig) apply(o *options) {
 		o.MaxSize = c.MaxSize
 	}
 
+	if c.MaxTotalSize > 0 {
+		o.MaxTotalSize = c.MaxTotalSize
+	}
+
+	if c.MaxBytes > 0 {
+		o.MaxBytes = c.MaxBytes
+	}
+
+	if c.MaxTotalBytes > 0 {
+		o.MaxTotalBytes = c.MaxTotalBytes
+	}
+
 	o.Compress = c.Compress
+	o.Compression = c.Compression
 	o.LocalTime = c.LocalTime
+	o.Schedule = c.Schedule
+}
+
+func (c RotationConfig) maxSize() int64 {
+	if c.MaxBytes > 0 {
+		return c.MaxBytes
+	}
+
+	if c.MaxSize > 0 {
+		return int64(c.MaxSize) * megabyte
+	}
+
+	return defaultMaxSize * megabyte
+}
+
+func (c RotationConfig) maxTotalSize() int64 {
+	if c.MaxTotalBytes > 0 {
+		return c.MaxTotalBytes
+	}
+
+	return int64(c.MaxTotalSize) * megabyte
 }
 
 type options struct {
 	RotationConfig
 
-	Level   Level
-	Format  Format
-	Encoder zapcore.Encoder
+	Level     Level
+	LevelSpec LevelSpec
+	Verbosity int
+	Format    Format
+	Encoder   zapcore.Encoder
 
 	Development bool
 
 	Output      io.Writer
+	SlogHandler slog.Handler
 	LogToStdout bool
+	LogToStderr bool
 	LogDirs     []string
 	LogFiles    []string
 
+	// SplitStreams replaces LogToStdout and LogToStderr, entries below
+	// SplitLevel go to stdout, the others to stderr.
+	SplitStreams bool
+	SplitLevel   Level
+
+	// Targets overrides the options above for single outputs.
+	Targets map[Target]targetOptions
+
 	AddCaller  bool
 	CallerSkip int
+
+	Stacktrace *StacktraceConfig
+
+	OnRotate        func(RotateEvent)
+	OnBackupRemoved func(path string)
 }
 
 func (o options) Clone() options {
 	c := options{
 		RotationConfig: o.RotationConfig,
 		Level:          o.Level,
+		LevelSpec:      o.LevelSpec,
+		Verbosity:      o.Verbosity,
 		Format:         o.Format,
 
 		Development: o.Development,
 
 		Output:      o.Output,
+		SlogHandler: o.SlogHandler,
 		LogToStdout: o.LogToStdout,
+		LogToStderr: o.LogToStderr,
+
+		SplitStreams: o.SplitStreams,
+		SplitLevel:   o.SplitLevel,
 
 		AddCaller:  o.AddCaller,
 		CallerSkip: o.CallerSkip,
+
+		Stacktrace: o.Stacktrace,
+
+		OnRotate:        o.OnRotate,
+		OnBackupRemoved: o.OnBackupRemoved,
 	}
 
 	if o.Encoder != nil {
@@ -80,11 +168,98 @@ func (o options) Clone() options {
 		copy(c.LogFiles, o.LogFiles)
 	}
 
+	if len(o.Targets) > 0 {
+		c.Targets = make(map[Target]targetOptions, len(o.Targets))
+
+		for t, to := range o.Targets {
+			c.Targets[t] = to
+		}
+	}
+
 	return c
 }
 
-func (o options) ZapLevelEnabled(lvl zapcore.Level) bool {
-	return o.Development || o.Level.Enabled(fromZapLevel(lvl))
+func (o options) rotateHooks() rotateHooks {
+	return rotateHooks{
+		onRotate:        o.OnRotate,
+		onBackupRemoved: o.OnBackupRemoved,
+	}
+}
+
+// ZapLevel is the initial level of the logger, development loggers enable
+// all levels.
+func (o options) ZapLevel() zapcore.Level {
+	if o.Development {
+		return zapcore.DebugLevel
+	}
+
+	return toZapLevel(o.Level)
+}
+
+// TargetLevelEnabled reports whether a single output is enabled at a level,
+// honoring its level set by WithTargetLevel. Outputs without their own
+// level follow the level of the logger.
+func (o options) TargetLevelEnabled(t Target, level zapcore.LevelEnabler) func(zapcore.Level) bool {
+	if lvl := o.Targets[t].Level; lvl != nil {
+		return func(l zapcore.Level) bool {
+			return lvl.Enabled(fromZapLevel(l))
+		}
+	}
+
+	return level.Enabled
+}
+
+func (o options) EncoderConfig() zapcore.EncoderConfig {
+	var encoderCfg zapcore.EncoderConfig
+
+	if o.Development {
+		encoderCfg = zap.NewDevelopmentEncoderConfig()
+		encoderCfg.EncodeTime = zapcore.RFC3339TimeEncoder
+		encoderCfg.EncodeCaller = zapcore.FullCallerEncoder
+	} else {
+		encoderCfg = zap.NewProductionEncoderConfig()
+		encoderCfg.TimeKey = "time"
+		encoderCfg.EncodeTime = zapcore.RFC3339TimeEncoder
+	}
+
+	return encoderCfg
+}
+
+// TargetEncoder returns the encoder of a single output, shared is the one
+// of all outputs without their own format or encoder config.
+func (o options) TargetEncoder(t Target, shared zapcore.Encoder) zapcore.Encoder {
+	to := o.Targets[t]
+
+	if to.Format == nil && to.EncoderConfig == nil {
+		return shared
+	}
+
+	encoderCfg := o.EncoderConfig()
+	if to.EncoderConfig != nil {
+		encoderCfg = *to.EncoderConfig
+	}
+
+	format := o.Format
+	if to.Format != nil {
+		format = *to.Format
+	}
+
+	return newEncoder(format, encoderCfg)
+}
+
+// setTarget updates the options of a single output. Targets is copied on
+// write since clones share it with the original.
+func (o *options) setTarget(t Target, fn func(*targetOptions)) {
+	targets := make(map[Target]targetOptions, len(o.Targets)+1)
+	for k, v := range o.Targets {
+		targets[k] = v
+	}
+
+	to := targets[t]
+	fn(&to)
+	targets[t] = to
+
+	o.Targets = targets
 }
 
 type Option interface {
@@ -103,6 +278,51 @@ func WithLevel(lvl Level) Option {
 	})
 }
 
+// WithLevelSpec sets the levels of named loggers, its "*" entry overrides
+// WithLevel. See LevelSpec.
+func WithLevelSpec(spec LevelSpec) Option {
+	return optionFunc(func(l *options) {
+		l.LevelSpec = spec
+	})
+}
+
+// WithVerbosity sets the glog-style verbosity, see Logger.V.
+func WithVerbosity(v int) Option {
+	return optionFunc(func(l *options) {
+		l.Verbosity = v
+	})
+}
+
+// WithTargetLevel sets the minimum level of a single output. Outputs
+// without their own level use the one set by WithLevel.
+func WithTargetLevel(target Target, lvl Level) Option {
+	return optionFunc(func(l *options) {
+		l.setTarget(target, func(to *targetOptions) {
+			to.Level = &lvl
+		})
+	})
+}
+
+// WithTargetFormat sets the format of a single output. Outputs without
+// their own format or encoder config share the encoder of the logger.
+func WithTargetFormat(target Target, format Format) Option {
+	return optionFunc(func(l *options) {
+		l.setTarget(target, func(to *targetOptions) {
+			to.Format = &format
+		})
+	})
+}
+
+// WithTargetEncoderConfig sets the encoder config of a single output. It
+// is encoded in the format set by WithTargetFormat, or by WithFormat.
+func WithTargetEncoderConfig(target Target, encoderCfg zapcore.EncoderConfig) Option {
+	return optionFunc(func(l *options) {
+		l.setTarget(target, func(to *targetOptions) {
+			to.EncoderConfig = &encoderCfg
+		})
+	})
+}
+
 func WithFormat(format Format) Option {
 	return optionFunc(func(l *options) {
 		l.Format = format
@@ -131,6 +351,15 @@ func WithOutput(output io.Writer) Option {
 	})
 }
 
+// WithSlogHandler sends the entries to h as well, with the slog levels
+// Debug to Error for DebugLevel to ErrorLevel and Error+4, Error+8 and so
+// on for the levels above.
+func WithSlogHandler(h slog.Handler) Option {
+	return optionFunc(func(l *options) {
+		l.SlogHandler = h
+	})
+}
+
 func LogToStdout() Option {
 	return WithLogToStdout(true)
 }
@@ -141,6 +370,36 @@ func WithLogToStdout(logToStdout bool) Option {
 	})
 }
 
+func LogToStderr() Option {
+	return WithLogToStderr(true)
+}
+
+func WithLogToStderr(logToStderr bool) Option {
+	return optionFunc(func(l *options) {
+		l.LogToStderr = logToStderr
+	})
+}
+
+// SplitStreams writes entries below the split level, WarnLevel by default,
+// to stdout and the others to stderr, instead of LogToStdout and
+// LogToStderr.
+func SplitStreams() Option {
+	return WithSplitStreams(true)
+}
+
+func WithSplitStreams(split bool) Option {
+	return optionFunc(func(l *options) {
+		l.SplitStreams = split
+	})
+}
+
+// WithSplitLevel sets the lowest level SplitStreams sends to stderr.
+func WithSplitLevel(lvl Level) Option {
+	return optionFunc(func(l *options) {
+		l.SplitLevel = lvl
+	})
+}
+
 func WithLogDirs(dirs ...string) Option {
 	return optionFunc(func(l *options) {
 		dst := make([]string, len(dirs))
@@ -174,3 +433,21 @@ func AddCallerSkip(skip int) Option {
 		l.CallerSkip += skip
 	})
 }
+
+// OnRotate registers fn to be called after a log file of the logger has
+// been rotated, either by Rotate or automatically. It runs in the
+// background once the backup has been compressed, calls for quick
+// rotations of one file may overlap.
+func OnRotate(fn func(RotateEvent)) Option {
+	return optionFunc(func(l *options) {
+		l.OnRotate = fn
+	})
+}
+
+// OnBackupRemoved registers fn to be called for every backup deleted by
+// the MaxBackups, MaxAge and MaxTotalSize retention.
+func OnBackupRemoved(fn func(path string)) Option {
+	return optionFunc(func(l *options) {
+		l.OnBackupRemoved = fn
+	})
+}
@@ -0,0 +1,154 @@
+// Package otellog adds the trace context of OpenTelemetry spans to the
+// entries of go.kuoruan.net/log, so that log lines can be correlated with
+// traces.
+package otellog
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"go.opentelemetry.io/otel/trace"
+
+	"go.kuoruan.net/log"
+)
+
+// ExtractorName is the name Register uses for log.RegisterContextExtractor.
+const ExtractorName = "otel"
+
+// Keys are the field names of the trace context. Empty names fall back to
+// DefaultKeys, except Traceparent, which adds the W3C traceparent of the
+// span as one more field when set.
+type Keys struct {
+	TraceID     string
+	SpanID      string
+	TraceFlags  string
+	Traceparent string
+}
+
+var DefaultKeys = Keys{
+	TraceID:    "trace_id",
+	SpanID:     "span_id",
+	TraceFlags: "trace_flags",
+}
+
+func (k Keys) withDefaults() Keys {
+	if k.TraceID == "" {
+		k.TraceID = DefaultKeys.TraceID
+	}
+
+	if k.SpanID == "" {
+		k.SpanID = DefaultKeys.SpanID
+	}
+
+	if k.TraceFlags == "" {
+		k.TraceFlags = DefaultKeys.TraceFlags
+	}
+
+	return k
+}
+
+// Extractor returns a log.ContextExtractor that adds the span context
+// carried by a context, if it is valid.
+func Extractor(keys Keys) log.ContextExtractor {
+	keys = keys.withDefaults()
+
+	return func(ctx context.Context) []interface{} {
+		sc := trace.SpanContextFromContext(ctx)
+		if !sc.IsValid() {
+			return nil
+		}
+
+		kv := []interface{}{
+			keys.TraceID, sc.TraceID().String(),
+			keys.SpanID, sc.SpanID().String(),
+			keys.TraceFlags, sc.TraceFlags().String(),
+		}
+
+		if keys.Traceparent != "" {
+			kv = append(kv, keys.Traceparent, FormatTraceparent(sc))
+		}
+
+		return kv
+	}
+}
+
+// Register registers Extractor(keys) with the ctx-aware log methods.
+func Register(keys Keys) {
+	log.RegisterContextExtractor(ExtractorName, Extractor(keys))
+}
+
+// FormatTraceparent returns sc in the W3C traceparent format.
+func FormatTraceparent(sc trace.SpanContext) string {
+	return fmt.Sprintf("00-%s-%s-%s", sc.TraceID(), sc.SpanID(), sc.TraceFlags())
+}
+
+// ParseTraceparent parses a W3C traceparent header into a remote span
+// context.
+func ParseTraceparent(traceparent string) (trace.SpanContext, error) {
+	parts := strings.Split(strings.TrimSpace(traceparent), "-")
+	if len(parts) < 4 {
+		return trace.SpanContext{}, fmt.Errorf("not a valid traceparent: %q", traceparent)
+	}
+
+	version, err := decodeHex(parts[0], 1)
+	if err != nil || version[0] == 0xff || (version[0] == 0 && len(parts) != 4) {
+		return trace.SpanContext{}, fmt.Errorf("not a valid traceparent: %q: unsupported version", traceparent)
+	}
+
+	var cfg trace.SpanContextConfig
+
+	traceID, err := decodeHex(parts[1], len(cfg.TraceID))
+	if err != nil {
+		return trace.SpanContext{}, fmt.Errorf("not a valid traceparent: %q: trace id: %v", traceparent, err)
+	}
+
+	spanID, err := decodeHex(parts[2], len(cfg.SpanID))
+	if err != nil {
+		return trace.SpanContext{}, fmt.Errorf("not a valid traceparent: %q: span id: %v", traceparent, err)
+	}
+
+	flags, err := decodeHex(parts[3], 1)
+	if err != nil {
+		return trace.SpanContext{}, fmt.Errorf("not a valid traceparent: %q: flags: %v", traceparent, err)
+	}
+
+	copy(cfg.TraceID[:], traceID)
+	copy(cfg.SpanID[:], spanID)
+	cfg.TraceFlags = trace.TraceFlags(flags[0]) & trace.FlagsSampled
+	cfg.Remote = true
+
+	sc := trace.NewSpanContext(cfg)
+	if !sc.IsValid() {
+		return trace.SpanContext{}, fmt.Errorf("not a valid traceparent: %q: zero id", traceparent)
+	}
+
+	return sc, nil
+}
+
+// ContextWithTraceparent returns a copy of ctx that carries the span
+// context of a W3C traceparent header, for services that do not run the
+// OpenTelemetry SDK.
+func ContextWithTraceparent(ctx context.Context, traceparent string) (context.Context, error) {
+	sc, err := ParseTraceparent(traceparent)
+	if err != nil {
+		return ctx, err
+	}
+
+	return trace.ContextWithRemoteSpanContext(ctx, sc), nil
+}
+
+// decodeHex decodes exactly n bytes of lower-case hex.
+func decodeHex(s string, n int) ([]byte, error) {
+	if len(s) != 2*n {
+		return nil, fmt.Errorf("expected %d hex digits, found %d", 2*n, len(s))
+	}
+
+	if strings.ToLower(s) != s {
+		return nil, errors.New("upper-case hex digits")
+	}
+
+	return hex.DecodeString(s)
+}
@@ -0,0 +1,81 @@
+package otellog
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/trace"
+
+	"go.kuoruan.net/log"
+)
+
+const traceparent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+func TestRegister(t *testing.T) {
+	Register(Keys{TraceID: "traceId", Traceparent: "traceparent"})
+	defer log.RegisterContextExtractor(ExtractorName, nil)
+
+	var buf bytes.Buffer
+
+	l := log.New(log.WithLogToStdout(false), log.WithOutput(&buf))
+
+	sc, err := ParseTraceparent(traceparent)
+	assert.NoError(t, err)
+
+	// the no-op tracer keeps the span context of the parent
+	ctx, span := trace.NewNoopTracerProvider().Tracer("test").Start(trace.ContextWithRemoteSpanContext(context.Background(), sc), "op")
+	defer span.End()
+
+	l.InfoCtx(ctx, "traced")
+	assert.Contains(t, buf.String(), `"msg":"traced","traceId":"4bf92f3577b34da6a3ce929d0e0e4736","span_id":"00f067aa0ba902b7","trace_flags":"01","traceparent":"`+traceparent+`"`)
+
+	buf.Reset()
+	l.InfoCtx(context.Background(), "untraced")
+	assert.NotContains(t, buf.String(), "span_id")
+}
+
+func TestParseTraceparent(t *testing.T) {
+	sc, err := ParseTraceparent(traceparent)
+	if assert.NoError(t, err) {
+		assert.True(t, sc.IsRemote())
+		assert.True(t, sc.IsSampled())
+		assert.Equal(t, traceparent, FormatTraceparent(sc))
+	}
+
+	// later versions may append fields
+	_, err = ParseTraceparent("01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00-extra")
+	assert.NoError(t, err)
+
+	for _, s := range []string{
+		"",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-1",
+		"00-4bf92f3577b34da6a3ce929d0e0e47-00f067aa0ba902b7-01",
+	} {
+		_, err := ParseTraceparent(s)
+		assert.Error(t, err, s)
+	}
+}
+
+func TestContextWithTraceparent(t *testing.T) {
+	ctx, err := ContextWithTraceparent(context.Background(), traceparent)
+	assert.NoError(t, err)
+	assert.Equal(t, traceparent, FormatTraceparent(trace.SpanContextFromContext(ctx)))
+
+	kv := Extractor(Keys{})(ctx)
+	assert.Equal(t, []interface{}{
+		"trace_id", "4bf92f3577b34da6a3ce929d0e0e4736",
+		"span_id", "00f067aa0ba902b7",
+		"trace_flags", "01",
+	}, kv)
+
+	_, err = ContextWithTraceparent(context.Background(), "bogus")
+	assert.Error(t, err)
+}
@@ -0,0 +1,720 @@
+package log
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/multierr"
+)
+
+const (
+	megabyte       = 1024 * 1024
+	defaultMaxSize = 100 // megabytes
+
+	backupTimeFormat = "2006-01-02T15-04-05.000"
+
+	// how often to check whether the file was moved by another process
+	movedCheckInterval = time.Second
+)
+
+// rotateWriter writes to a log file and cuts it when it would grow beyond
+// maxSize or when a schedule boundary passes. The old file is renamed to
+// name-<timestamp>.ext, then compressed and pruned in the background.
+//
+// A lock file next to the log coordinates rotations between processes
+// writing the same file; a writer whose file was rotated by someone else
+// reopens the path instead of rotating again.
+type rotateWriter struct {
+	mu     sync.Mutex
+	file   *os.File
+	closed bool
+
+	path   string
+	refs   int // guarded by rotateWriters
+	config RotationConfig
+
+	size         int64
+	maxSize      int64
+	maxAge       time.Duration
+	maxBackups   int
+	maxTotalSize int64
+	compressor   Compressor
+	group        []string
+
+	now      func() time.Time
+	location *time.Location
+	schedule Schedule
+	next     time.Time
+	checked  time.Time
+
+	millMu sync.Mutex
+	millWG sync.WaitGroup
+
+	hooksMu sync.Mutex
+	hooks   map[*writerRef]rotateHooks
+}
+
+// RotateEvent describes the rotation of a log file.
+type RotateEvent struct {
+	// Filename is the active log file.
+	Filename string
+	// Backup is the name the file was renamed to.
+	Backup string
+	// Compressed is the name of the compressed backup, empty if the
+	// backup was not compressed.
+	Compressed string
+}
+
+type rotateHooks struct {
+	onRotate        func(RotateEvent)
+	onBackupRemoved func(path string)
+}
+
+func (h rotateHooks) empty() bool {
+	return h.onRotate == nil && h.onBackupRemoved == nil
+}
+
+func newRotateWriter(path string, config RotationConfig) *rotateWriter {
+	w := &rotateWriter{
+		path: path,
+		now:  time.Now,
+	}
+
+	w.configure(config)
+
+	return w
+}
+
+// configure applies the settings of config. Once the writer is in use it
+// must be called with w.millMu and w.mu held, see reconfigure.
+func (w *rotateWriter) configure(config RotationConfig) {
+	w.config = config
+	w.maxSize = config.maxSize()
+	w.maxAge = time.Duration(config.MaxAge) * 24 * time.Hour
+	w.maxBackups = config.MaxBackups
+	w.maxTotalSize = config.maxTotalSize()
+	w.location = time.UTC
+	w.compressor = nil
+	w.schedule = nil
+	w.next = time.Time{}
+
+	if config.LocalTime {
+		w.location = time.Local
+	}
+
+	if config.Compress {
+		c, err := lookupCompressor(config.Compression)
+		if err != nil {
+			reportError(fmt.Errorf("compression of %s disabled: %v", w.path, err))
+		} else {
+			w.compressor = c
+		}
+	}
+
+	if config.Schedule != "" {
+		schedule, err := ParseSchedule(config.Schedule)
+		if err != nil {
+			reportError(fmt.Errorf("time-based rotation of %s disabled: %v", w.path, err))
+		} else {
+			w.setSchedule(schedule)
+		}
+	}
+}
+
+// reconfigure applies config to a writer in use, after pending compression
+// and cleanup have finished with the old settings.
+func (w *rotateWriter) reconfigure(config RotationConfig) {
+	w.millMu.Lock()
+	defer w.millMu.Unlock()
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if config != w.config {
+		w.configure(config)
+	}
+}
+
+// setSchedule enables time-based rotation. An existing file is cut at the
+// first boundary after its last modification, so entries written before a
+// restart do not end up in the wrong period.
+func (w *rotateWriter) setSchedule(schedule Schedule) {
+	w.schedule = schedule
+
+	from := w.now()
+	if info, err := os.Stat(w.path); err == nil {
+		from = info.ModTime()
+	}
+
+	w.next = schedule.Next(from.In(w.location))
+}
+
+func (w *rotateWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return 0, errWriterClosed
+	}
+
+	if w.file == nil {
+		if err := w.openExistingOrNew(len(p)); err != nil {
+			return 0, err
+		}
+	} else if err := w.reopenIfMoved(); err != nil {
+		return 0, err
+	}
+
+	// an entry larger than maxSize still gets written, into a file of its own
+	if w.due() || w.size > 0 && w.size+int64(len(p)) > w.maxSize {
+		if err := w.rotate(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := w.file.Write(p)
+	w.size += int64(n)
+
+	return n, err
+}
+
+func (w *rotateWriter) Sync() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.file == nil {
+		return nil
+	}
+
+	return w.file.Sync()
+}
+
+func (w *rotateWriter) Rotate() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return errWriterClosed
+	}
+
+	return w.rotate()
+}
+
+// Reopen closes the file and opens the path again without renaming it, for
+// files that have been moved by an external tool such as logrotate.
+func (w *rotateWriter) Reopen() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return errWriterClosed
+	}
+
+	if w.file != nil {
+		err := w.file.Close()
+		w.file = nil
+
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(w.path), 0755); err != nil {
+		return err
+	}
+
+	return w.open()
+}
+
+// Close closes the file and waits for pending compression and cleanup.
+// The file is never reopened.
+func (w *rotateWriter) Close() error {
+	w.mu.Lock()
+
+	if w.closed {
+		w.mu.Unlock()
+		return nil
+	}
+
+	w.closed = true
+
+	var err error
+	if w.file != nil {
+		err = w.file.Close()
+		w.file = nil
+	}
+
+	w.mu.Unlock()
+
+	w.millWG.Wait()
+
+	return err
+}
+
+// due reports whether a schedule boundary has passed.
+func (w *rotateWriter) due() bool {
+	return w.schedule != nil && !w.next.IsZero() && !w.now().Before(w.next)
+}
+
+// openExistingOrNew opens the log file for appending, rotating it first if
+// it is due or if writeLen would not fit. It must be called with w.mu held.
+func (w *rotateWriter) openExistingOrNew(writeLen int) error {
+	info, err := os.Stat(w.path)
+	if os.IsNotExist(err) {
+		return w.openNew(0600)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if w.due() || info.Size() > 0 && info.Size()+int64(writeLen) > w.maxSize {
+		return w.rotate()
+	}
+
+	return w.open()
+}
+
+func (w *rotateWriter) open() error {
+	f, err := os.OpenFile(w.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return err
+	}
+
+	w.file = f
+	w.size = info.Size()
+	w.checked = w.now()
+
+	return nil
+}
+
+func (w *rotateWriter) openNew(mode os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(w.path), 0755); err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(w.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, mode)
+	if err != nil {
+		return err
+	}
+
+	w.file = f
+	w.size = 0
+	w.checked = w.now()
+
+	return nil
+}
+
+// moved reports whether the path no longer refers to the open file.
+func (w *rotateWriter) moved() bool {
+	info, err := w.file.Stat()
+	if err != nil {
+		return true
+	}
+
+	current, err := os.Stat(w.path)
+	if err != nil {
+		return true
+	}
+
+	return !os.SameFile(info, current)
+}
+
+// reopenIfMoved follows rotations done by other processes, checking at most
+// once per movedCheckInterval. It must be called with w.mu held.
+func (w *rotateWriter) reopenIfMoved() error {
+	now := w.now()
+	if now.Sub(w.checked) < movedCheckInterval {
+		return nil
+	}
+
+	w.checked = now
+
+	if !w.moved() {
+		return nil
+	}
+
+	_ = w.file.Close()
+	w.file = nil
+
+	return w.open()
+}
+
+// rotate renames the log file to a backup and opens a new one. It must be
+// called with w.mu held.
+func (w *rotateWriter) rotate() error {
+	if err := os.MkdirAll(filepath.Dir(w.path), 0755); err != nil {
+		return err
+	}
+
+	unlock, err := lockFile(w.path + ".lock")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if w.schedule != nil {
+		w.next = w.schedule.Next(w.now().In(w.location))
+	}
+
+	if w.file != nil {
+		moved := w.moved()
+
+		err := w.file.Close()
+		w.file = nil
+
+		// another process already rotated, continue with its new file
+		if moved {
+			return w.open()
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	// the new file keeps the mode of the old one
+	mode := os.FileMode(0600)
+
+	if info, err := os.Stat(w.path); err == nil {
+		mode = info.Mode()
+
+		backup := w.backupName()
+
+		if err := os.Rename(w.path, backup); err != nil {
+			return err
+		}
+
+		w.mill(backup)
+	}
+
+	return w.openNew(mode)
+}
+
+// backupName returns an unused backup name for the current time.
+func (w *rotateWriter) backupName() string {
+	dir := filepath.Dir(w.path)
+	prefix, ext := backupPrefixAndExt(w.path)
+
+	t := w.now().In(w.location)
+
+	for {
+		name := filepath.Join(dir, prefix+t.Format(backupTimeFormat)+ext)
+
+		if !backupExists(name) {
+			return name
+		}
+
+		t = t.Add(time.Millisecond)
+	}
+}
+
+func backupExists(name string) bool {
+	for _, suffix := range append([]string{""}, compressionSuffixes()...) {
+		if _, err := os.Lstat(name + suffix); err == nil {
+			return true
+		}
+	}
+
+	return false
+}
+
+func backupPrefixAndExt(path string) (prefix, ext string) {
+	filename := filepath.Base(path)
+	ext = filepath.Ext(filename)
+	prefix = filename[:len(filename)-len(ext)] + "-"
+
+	return prefix, ext
+}
+
+// mill compresses and prunes backups in the background, then runs the
+// hooks for the new backup outside of the write path. The hooks run
+// without millMu, so they can open the file again, e.g. with WithOptions.
+func (w *rotateWriter) mill(backup string) {
+	hooks := w.currentHooks()
+
+	if len(hooks) == 0 && w.maxBackups <= 0 && w.maxAge <= 0 && w.maxTotalSize <= 0 && w.compressor == nil {
+		return
+	}
+
+	w.millWG.Add(1)
+
+	go func() {
+		defer w.millWG.Done()
+
+		w.millMu.Lock()
+
+		removed, err := w.millRunOnce()
+		if err != nil {
+			reportError(fmt.Errorf("clean up backups of %s: %v", w.path, err))
+		}
+
+		e := RotateEvent{
+			Filename: w.path,
+			Backup:   backup,
+		}
+
+		// the backup may also have been compressed by an earlier run
+		if w.compressor != nil {
+			if _, err := os.Stat(backup + w.compressor.Suffix()); err == nil {
+				e.Compressed = backup + w.compressor.Suffix()
+			}
+		}
+
+		w.millMu.Unlock()
+
+		for _, h := range hooks {
+			for _, path := range removed {
+				if h.onBackupRemoved != nil {
+					h.onBackupRemoved(path)
+				}
+			}
+
+			if h.onRotate != nil {
+				h.onRotate(e)
+			}
+		}
+	}()
+}
+
+func (w *rotateWriter) addHooks(ref *writerRef, h rotateHooks) {
+	if h.empty() {
+		return
+	}
+
+	w.hooksMu.Lock()
+	defer w.hooksMu.Unlock()
+
+	if w.hooks == nil {
+		w.hooks = make(map[*writerRef]rotateHooks)
+	}
+
+	w.hooks[ref] = h
+}
+
+func (w *rotateWriter) removeHooks(ref *writerRef) {
+	w.hooksMu.Lock()
+	defer w.hooksMu.Unlock()
+
+	delete(w.hooks, ref)
+}
+
+func (w *rotateWriter) currentHooks() []rotateHooks {
+	w.hooksMu.Lock()
+	defer w.hooksMu.Unlock()
+
+	hooks := make([]rotateHooks, 0, len(w.hooks))
+	for _, h := range w.hooks {
+		hooks = append(hooks, h)
+	}
+
+	return hooks
+}
+
+// millRunOnce removes backups beyond MaxBackups and MaxAge, compresses the
+// remaining ones, then enforces MaxTotalSize across the group. It returns
+// the backups it removed.
+func (w *rotateWriter) millRunOnce() (removed []string, err error) {
+	unlock, err := lockFile(w.path + ".lock")
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	backups, err := listBackups(w.path, w.location)
+	if err != nil {
+		return nil, err
+	}
+
+	sortBackups(backups)
+
+	var remove, remaining []backupFile
+
+	if w.maxBackups > 0 {
+		// a backup and its compressed copy count once
+		preserved := make(map[time.Time]bool)
+
+		for _, b := range backups {
+			preserved[b.time] = true
+
+			if len(preserved) > w.maxBackups {
+				remove = append(remove, b)
+			} else {
+				remaining = append(remaining, b)
+			}
+		}
+
+		backups, remaining = remaining, nil
+	}
+
+	if w.maxAge > 0 {
+		cutoff := w.now().Add(-w.maxAge)
+
+		for _, b := range backups {
+			if b.time.Before(cutoff) {
+				remove = append(remove, b)
+			} else {
+				remaining = append(remaining, b)
+			}
+		}
+
+		backups = remaining
+	}
+
+	removed, err = removeBackups(remove)
+
+	if w.compressor != nil {
+		for _, b := range backups {
+			if b.compressed {
+				continue
+			}
+
+			if _, errCompress := compressFile(b.path, w.compressor); errCompress != nil {
+				err = multierr.Append(err, errCompress)
+			}
+		}
+	}
+
+	excess, errExcess := w.removeExcessBackups()
+
+	return append(removed, excess...), multierr.Append(err, errExcess)
+}
+
+func removeBackups(backups []backupFile) (removed []string, err error) {
+	for _, b := range backups {
+		if errRemove := os.Remove(b.path); errRemove != nil {
+			if !os.IsNotExist(errRemove) {
+				err = multierr.Append(err, errRemove)
+			}
+
+			continue
+		}
+
+		removed = append(removed, b.path)
+	}
+
+	return removed, err
+}
+
+// removeExcessBackups deletes the oldest backups of the file and its group
+// until their total size fits in maxTotalSize.
+func (w *rotateWriter) removeExcessBackups() (removed []string, err error) {
+	if w.maxTotalSize <= 0 {
+		return nil, nil
+	}
+
+	var backups []backupFile
+
+	for _, path := range append([]string{w.path}, w.group...) {
+		files, err := listBackups(path, w.location)
+		if err != nil {
+			return nil, err
+		}
+
+		backups = append(backups, files...)
+	}
+
+	return removeBackups(excessBackups(backups, w.maxTotalSize))
+}
+
+type backupFile struct {
+	path       string
+	time       time.Time
+	size       int64
+	compressed bool
+}
+
+// listBackups returns the backups of path, compressed or not. Their names
+// hold the time of rotation in loc.
+func listBackups(path string, loc *time.Location) ([]backupFile, error) {
+	dir := filepath.Dir(path)
+	prefix, ext := backupPrefixAndExt(path)
+	suffixes := compressionSuffixes()
+
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	var backups []backupFile
+
+	for _, info := range infos {
+		name := info.Name()
+
+		if info.IsDir() || !strings.HasPrefix(name, prefix) {
+			continue
+		}
+
+		ts := name[len(prefix):]
+		compressed := false
+
+		for _, suffix := range suffixes {
+			if suffix != "" && strings.HasSuffix(ts, ext+suffix) {
+				ts = strings.TrimSuffix(ts, suffix)
+				compressed = true
+
+				break
+			}
+		}
+
+		if !strings.HasSuffix(ts, ext) {
+			continue
+		}
+
+		// not created by us if it does not parse
+		t, err := time.ParseInLocation(backupTimeFormat, ts[:len(ts)-len(ext)], loc)
+		if err != nil {
+			continue
+		}
+
+		backups = append(backups, backupFile{
+			path:       filepath.Join(dir, name),
+			time:       t,
+			size:       info.Size(),
+			compressed: compressed,
+		})
+	}
+
+	return backups, nil
+}
+
+// sortBackups sorts backups newest first.
+func sortBackups(backups []backupFile) {
+	sort.SliceStable(backups, func(i, j int) bool {
+		return backups[i].time.After(backups[j].time)
+	})
+}
+
+// excessBackups returns the oldest backups that have to go so that the
+// total size does not exceed max.
+func excessBackups(backups []backupFile, max int64) []backupFile {
+	sortBackups(backups)
+
+	var total int64
+
+	for i, b := range backups {
+		total += b.size
+
+		if total > max {
+			return backups[i:]
+		}
+	}
+
+	return nil
+}
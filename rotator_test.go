@@ -0,0 +1,621 @@
+package log
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Date(2021, 3, 5, 10, 17, 0, 0, time.UTC)}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.now
+}
+
+func (c *fakeClock) Add(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.now = c.now.Add(d)
+}
+
+func newTestRotateWriter(t *testing.T, path string, config RotationConfig, clock *fakeClock) *rotateWriter {
+	t.Helper()
+
+	schedule := config.Schedule
+	config.Schedule = ""
+
+	w := newRotateWriter(path, config)
+	w.now = clock.Now
+
+	if schedule != "" {
+		s, err := ParseSchedule(schedule)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		w.setSchedule(s)
+	}
+
+	t.Cleanup(func() {
+		_ = w.Close()
+	})
+
+	return w
+}
+
+func mustWrite(t *testing.T, w io.Writer, s string) {
+	t.Helper()
+
+	n, err := w.Write([]byte(s))
+	assert.NoError(t, err)
+	assert.Equal(t, len(s), n)
+}
+
+func readFile(t *testing.T, name string) string {
+	t.Helper()
+
+	content, err := ioutil.ReadFile(name)
+	assert.NoError(t, err)
+
+	return string(content)
+}
+
+func readDir(t *testing.T, dir string) []string {
+	t.Helper()
+
+	infos, err := ioutil.ReadDir(dir)
+	assert.NoError(t, err)
+
+	names := make([]string, 0, len(infos))
+	for _, info := range infos {
+		if filepath.Ext(info.Name()) == ".lock" {
+			continue
+		}
+
+		names = append(names, info.Name())
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+func writeBackup(t *testing.T, dir, prefix, ext string, ts time.Time, size int) string {
+	t.Helper()
+
+	name := filepath.Join(dir, prefix+"-"+ts.Format(backupTimeFormat)+ext)
+	assert.NoError(t, ioutil.WriteFile(name, make([]byte, size), 0644))
+
+	return name
+}
+
+func TestRotateWriter_Write(t *testing.T) {
+	dir := tempDir(t)
+	file := filepath.Join(dir, "nested", "app.log")
+
+	w := newTestRotateWriter(t, file, RotationConfig{}, newFakeClock())
+
+	mustWrite(t, w, "first\n")
+	mustWrite(t, w, "second\n")
+	assert.NoError(t, w.Sync())
+
+	assert.Equal(t, "first\nsecond\n", readFile(t, file))
+
+	info, err := os.Stat(file)
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+}
+
+func TestRotateWriter_AppendExisting(t *testing.T) {
+	dir := tempDir(t)
+	file := filepath.Join(dir, "app.log")
+
+	assert.NoError(t, ioutil.WriteFile(file, []byte("old\n"), 0640))
+
+	w := newTestRotateWriter(t, file, RotationConfig{}, newFakeClock())
+	mustWrite(t, w, "new\n")
+
+	assert.Equal(t, "old\nnew\n", readFile(t, file))
+	assert.Equal(t, []string{"app.log"}, readDir(t, dir))
+}
+
+func TestRotateWriter_MaxBytes(t *testing.T) {
+	dir := tempDir(t)
+	file := filepath.Join(dir, "app.log")
+	clock := newFakeClock()
+
+	w := newTestRotateWriter(t, file, RotationConfig{MaxBytes: 10}, clock)
+
+	mustWrite(t, w, "12345\n")
+	clock.Add(time.Second)
+	mustWrite(t, w, "67890\n")
+
+	backup := "app-" + clock.Now().Format(backupTimeFormat) + ".log"
+
+	assert.Equal(t, []string{backup, "app.log"}, readDir(t, dir))
+	assert.Equal(t, "12345\n", readFile(t, filepath.Join(dir, backup)))
+	assert.Equal(t, "67890\n", readFile(t, file))
+}
+
+func TestRotateWriter_ExistingTooLarge(t *testing.T) {
+	dir := tempDir(t)
+	file := filepath.Join(dir, "app.log")
+
+	assert.NoError(t, ioutil.WriteFile(file, []byte("0123456789"), 0640))
+
+	w := newTestRotateWriter(t, file, RotationConfig{MaxBytes: 10}, newFakeClock())
+	mustWrite(t, w, "new\n")
+
+	assert.Len(t, readDir(t, dir), 2)
+	assert.Equal(t, "new\n", readFile(t, file))
+
+	// the new file keeps the mode of the old one
+	info, err := os.Stat(file)
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0640), info.Mode().Perm())
+}
+
+func TestRotateWriter_OversizedEntry(t *testing.T) {
+	dir := tempDir(t)
+	file := filepath.Join(dir, "app.log")
+
+	w := newTestRotateWriter(t, file, RotationConfig{MaxBytes: 4}, newFakeClock())
+
+	mustWrite(t, w, "0123456789\n")
+	assert.Equal(t, []string{"app.log"}, readDir(t, dir))
+
+	mustWrite(t, w, "a\n")
+	assert.Len(t, readDir(t, dir), 2)
+	assert.Equal(t, "a\n", readFile(t, file))
+}
+
+func TestRotateWriter_BackupNameCollision(t *testing.T) {
+	dir := tempDir(t)
+	file := filepath.Join(dir, "app.log")
+	clock := newFakeClock()
+
+	w := newTestRotateWriter(t, file, RotationConfig{}, clock)
+
+	for i := 0; i < 3; i++ {
+		mustWrite(t, w, "line\n")
+		assert.NoError(t, w.Rotate())
+	}
+
+	now := clock.Now()
+
+	assert.Equal(t, []string{
+		"app-" + now.Format(backupTimeFormat) + ".log",
+		"app-" + now.Add(time.Millisecond).Format(backupTimeFormat) + ".log",
+		"app-" + now.Add(2*time.Millisecond).Format(backupTimeFormat) + ".log",
+		"app.log",
+	}, readDir(t, dir))
+}
+
+func TestRotateWriter_LocalTime(t *testing.T) {
+	dir := tempDir(t)
+	file := filepath.Join(dir, "app.log")
+	clock := newFakeClock()
+	loc := time.FixedZone("UTC+8", 8*60*60)
+
+	w := newTestRotateWriter(t, file, RotationConfig{LocalTime: true}, clock)
+	w.location = loc
+
+	mustWrite(t, w, "line\n")
+	assert.NoError(t, w.Rotate())
+
+	assert.Contains(t, readDir(t, dir), "app-"+clock.Now().In(loc).Format(backupTimeFormat)+".log")
+}
+
+func TestRotateWriter_MaxBackups(t *testing.T) {
+	dir := tempDir(t)
+	file := filepath.Join(dir, "app.log")
+	clock := newFakeClock()
+
+	w := newTestRotateWriter(t, file, RotationConfig{MaxBackups: 2}, clock)
+
+	var backups []string
+
+	for i := 0; i < 5; i++ {
+		mustWrite(t, w, "line\n")
+		backups = append(backups, "app-"+clock.Now().Format(backupTimeFormat)+".log")
+
+		assert.NoError(t, w.Rotate())
+		w.millWG.Wait()
+
+		clock.Add(time.Minute)
+	}
+
+	assert.Equal(t, append(backups[3:], "app.log"), readDir(t, dir))
+}
+
+func TestRotateWriter_MaxAge(t *testing.T) {
+	dir := tempDir(t)
+	file := filepath.Join(dir, "app.log")
+	clock := newFakeClock()
+
+	expired := writeBackup(t, dir, "app", ".log", clock.Now().Add(-49*time.Hour), 1)
+	kept := writeBackup(t, dir, "app", ".log", clock.Now().Add(-47*time.Hour), 1)
+
+	w := newTestRotateWriter(t, file, RotationConfig{MaxAge: 2}, clock)
+
+	mustWrite(t, w, "line\n")
+	assert.NoError(t, w.Rotate())
+	w.millWG.Wait()
+
+	_, err := os.Stat(expired)
+	assert.True(t, os.IsNotExist(err))
+
+	_, err = os.Stat(kept)
+	assert.NoError(t, err)
+}
+
+func TestRotateWriter_Compress(t *testing.T) {
+	dir := tempDir(t)
+	file := filepath.Join(dir, "app.log")
+	clock := newFakeClock()
+
+	w := newTestRotateWriter(t, file, RotationConfig{Compress: true}, clock)
+
+	mustWrite(t, w, "compressed\n")
+	assert.NoError(t, w.Rotate())
+	w.millWG.Wait()
+
+	backup := filepath.Join(dir, "app-"+clock.Now().Format(backupTimeFormat)+".log.gz")
+	assert.Equal(t, []string{filepath.Base(backup), "app.log"}, readDir(t, dir))
+
+	f, err := os.Open(backup)
+	assert.NoError(t, err)
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	assert.NoError(t, err)
+
+	content, err := ioutil.ReadAll(gz)
+	assert.NoError(t, err)
+	assert.Equal(t, "compressed\n", string(content))
+}
+
+type reverseCompressor struct{}
+
+func (reverseCompressor) Suffix() string {
+	return ".rev"
+}
+
+func (reverseCompressor) Compress(dst io.Writer, src io.Reader) error {
+	b, err := ioutil.ReadAll(src)
+	if err != nil {
+		return err
+	}
+
+	for i, j := 0, len(b)-1; i < j; i, j = i+1, j-1 {
+		b[i], b[j] = b[j], b[i]
+	}
+
+	_, err = dst.Write(b)
+
+	return err
+}
+
+func TestRotateWriter_CustomCompressor(t *testing.T) {
+	RegisterCompressor("reverse", reverseCompressor{})
+
+	dir := tempDir(t)
+	file := filepath.Join(dir, "app.log")
+	clock := newFakeClock()
+
+	w := newTestRotateWriter(t, file, RotationConfig{Compress: true, Compression: "reverse", MaxBackups: 1}, clock)
+
+	mustWrite(t, w, "abc")
+	assert.NoError(t, w.Rotate())
+	w.millWG.Wait()
+
+	backup := filepath.Join(dir, "app-"+clock.Now().Format(backupTimeFormat)+".log.rev")
+	assert.Equal(t, "cba", readFile(t, backup))
+
+	// compressed backups count towards MaxBackups
+	clock.Add(time.Minute)
+	mustWrite(t, w, "def")
+	assert.NoError(t, w.Rotate())
+	w.millWG.Wait()
+
+	_, err := os.Stat(backup)
+	assert.True(t, os.IsNotExist(err))
+	assert.Len(t, readDir(t, dir), 2)
+}
+
+func TestRotateWriter_UnknownCompressor(t *testing.T) {
+	w := newTestRotateWriter(t, filepath.Join(tempDir(t), "app.log"), RotationConfig{Compress: true, Compression: "bogus"}, newFakeClock())
+
+	assert.Nil(t, w.compressor)
+}
+
+func TestRotateWriter_Schedule(t *testing.T) {
+	dir := tempDir(t)
+	file := filepath.Join(dir, "time.log")
+	clock := newFakeClock()
+
+	w := newTestRotateWriter(t, file, RotationConfig{Schedule: "@hourly"}, clock)
+
+	mustWrite(t, w, "first\n")
+	assert.Len(t, readDir(t, dir), 1)
+
+	clock.Add(30 * time.Minute)
+	mustWrite(t, w, "second\n")
+	assert.Len(t, readDir(t, dir), 1)
+
+	clock.Add(15 * time.Minute)
+	mustWrite(t, w, "third\n")
+	assert.Len(t, readDir(t, dir), 2)
+
+	assert.Equal(t, "third\n", readFile(t, file))
+	assert.Equal(t, time.Date(2021, 3, 5, 12, 0, 0, 0, time.UTC), w.next)
+}
+
+func TestRotateWriter_ScheduleExistingFile(t *testing.T) {
+	dir := tempDir(t)
+	file := filepath.Join(dir, "time.log")
+	clock := newFakeClock()
+
+	assert.NoError(t, ioutil.WriteFile(file, []byte("yesterday\n"), 0600))
+
+	yesterday := clock.Now().Add(-24 * time.Hour)
+	assert.NoError(t, os.Chtimes(file, yesterday, yesterday))
+
+	w := newTestRotateWriter(t, file, RotationConfig{Schedule: "@daily"}, clock)
+	mustWrite(t, w, "today\n")
+
+	assert.Len(t, readDir(t, dir), 2)
+	assert.Equal(t, "today\n", readFile(t, file))
+}
+
+func TestRotateWriter_InvalidSchedule(t *testing.T) {
+	w := newRotateWriter(filepath.Join(tempDir(t), "time.log"), RotationConfig{Schedule: "bogus"})
+	defer w.Close()
+
+	assert.Nil(t, w.schedule)
+}
+
+func TestExcessBackups(t *testing.T) {
+	now := time.Now()
+
+	backups := []backupFile{
+		{path: "b", time: now.Add(-2 * time.Hour), size: 4},
+		{path: "a", time: now.Add(-1 * time.Hour), size: 4},
+		{path: "d", time: now.Add(-4 * time.Hour), size: 4},
+		{path: "c", time: now.Add(-3 * time.Hour), size: 4},
+	}
+
+	assert.Nil(t, excessBackups(backups, 16))
+	assert.Equal(t, []backupFile{backups[2], backups[3]}, excessBackups(backups, 11))
+	assert.Len(t, excessBackups(backups, 3), 4)
+}
+
+func TestRotateWriter_MaxTotalBytes(t *testing.T) {
+	dir := tempDir(t)
+	file := filepath.Join(dir, "app.log")
+	now := time.Now().UTC()
+
+	oldest := writeBackup(t, dir, "app", ".log.gz", now.Add(-3*time.Hour), 10)
+	older := writeBackup(t, dir, "app", ".log", now.Add(-2*time.Hour), 10)
+	newer := writeBackup(t, dir, "app", ".log", now.Add(-1*time.Hour), 10)
+	other := writeBackup(t, dir, "other", ".log", now.Add(-4*time.Hour), 10)
+
+	w := newTestRotateWriter(t, file, RotationConfig{MaxTotalBytes: 15}, newFakeClock())
+	removed, err := w.removeExcessBackups()
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{oldest, older}, removed)
+
+	for _, f := range []string{oldest, older} {
+		_, err := os.Stat(f)
+		assert.True(t, os.IsNotExist(err), f)
+	}
+
+	for _, f := range []string{newer, other} {
+		_, err := os.Stat(f)
+		assert.NoError(t, err, f)
+	}
+}
+
+func TestRotateWriter_MaxTotalBytesGroup(t *testing.T) {
+	dir := tempDir(t)
+	now := time.Now().UTC()
+
+	debug := writeBackup(t, dir, "debug", ".log", now.Add(-2*time.Hour), 10)
+	info := writeBackup(t, dir, "info", ".log", now.Add(-1*time.Hour), 10)
+
+	group := []string{filepath.Join(dir, "debug.log"), filepath.Join(dir, "info.log")}
+
+	w := openRotateWriter(group[1], RotationConfig{MaxTotalBytes: 15}, rotateHooks{}, group...)
+	defer w.Close()
+
+	mustWrite(t, w, "info\n")
+	assert.NoError(t, w.Rotate())
+	w.millWG.Wait()
+
+	_, err := os.Stat(debug)
+	assert.True(t, os.IsNotExist(err))
+
+	// the fresh backup holds the 5 bytes written above
+	_, err = os.Stat(info)
+	assert.NoError(t, err)
+}
+
+func TestRotateWriter_MaxTotalBytesOnSizeRotation(t *testing.T) {
+	dir := tempDir(t)
+	file := filepath.Join(dir, "app.log")
+	clock := newFakeClock()
+
+	old := writeBackup(t, dir, "app", ".log", clock.Now().Add(-time.Hour), 10)
+
+	w := newTestRotateWriter(t, file, RotationConfig{MaxBytes: 8, MaxTotalBytes: 8}, clock)
+
+	mustWrite(t, w, "1234\n")
+	mustWrite(t, w, "5678\n")
+	w.millWG.Wait()
+
+	_, err := os.Stat(old)
+	assert.True(t, os.IsNotExist(err))
+	assert.Len(t, readDir(t, dir), 2)
+}
+
+func TestRotateWriter_Close(t *testing.T) {
+	dir := tempDir(t)
+	file := filepath.Join(dir, "app.log")
+
+	w := newTestRotateWriter(t, file, RotationConfig{}, newFakeClock())
+
+	mustWrite(t, w, "line\n")
+	assert.NoError(t, w.Close())
+	assert.NoError(t, w.Close())
+	assert.NoError(t, os.Remove(file))
+
+	_, err := w.Write([]byte("dropped\n"))
+	assert.Equal(t, errWriterClosed, err)
+	assert.Equal(t, errWriterClosed, w.Rotate())
+
+	_, err = os.Stat(file)
+	assert.True(t, os.IsNotExist(err))
+}
+
+// Two writers on one path stand in for two processes sharing a log file.
+func TestRotateWriter_SharedBetweenProcesses(t *testing.T) {
+	dir := tempDir(t)
+	file := filepath.Join(dir, "app.log")
+	clock := newFakeClock()
+
+	a := newTestRotateWriter(t, file, RotationConfig{}, clock)
+	b := newTestRotateWriter(t, file, RotationConfig{}, clock)
+
+	mustWrite(t, a, "a1\n")
+	mustWrite(t, b, "b1\n")
+
+	assert.NoError(t, a.Rotate())
+
+	// b follows a's rotation rather than renaming the new file again
+	assert.NoError(t, b.Rotate())
+	assert.Len(t, readDir(t, dir), 2)
+
+	mustWrite(t, a, "a2\n")
+	mustWrite(t, b, "b2\n")
+	assert.Equal(t, "a2\nb2\n", readFile(t, file))
+
+	assert.NoError(t, a.Rotate())
+
+	// b notices the move on its own after a while
+	clock.Add(movedCheckInterval)
+	mustWrite(t, b, "b3\n")
+	assert.Equal(t, "b3\n", readFile(t, file))
+
+	var backups bytes.Buffer
+	for _, name := range readDir(t, dir) {
+		if name != "app.log" {
+			backups.WriteString(readFile(t, filepath.Join(dir, name)))
+		}
+	}
+
+	assert.Equal(t, "a1\nb1\na2\nb2\n", backups.String())
+}
+
+func TestRotateWriter_Hooks(t *testing.T) {
+	dir := tempDir(t)
+	file := filepath.Join(dir, "app.log")
+	clock := newFakeClock()
+
+	old := writeBackup(t, dir, "app", ".log.gz", clock.Now().Add(-time.Hour), 1)
+
+	var (
+		events  []RotateEvent
+		removed []string
+	)
+
+	w := openRotateWriter(file, RotationConfig{Compress: true, MaxBackups: 1}, rotateHooks{
+		onRotate: func(e RotateEvent) {
+			events = append(events, e)
+		},
+		onBackupRemoved: func(path string) {
+			removed = append(removed, path)
+		},
+	})
+	defer w.Close()
+
+	w.now = clock.Now
+
+	mustWrite(t, w, "line\n")
+	assert.NoError(t, w.Rotate())
+	w.millWG.Wait()
+
+	backup := filepath.Join(dir, "app-"+clock.Now().Format(backupTimeFormat)+".log")
+
+	assert.Equal(t, []RotateEvent{{
+		Filename:   file,
+		Backup:     backup,
+		Compressed: backup + ".gz",
+	}}, events)
+	assert.Equal(t, []string{old}, removed)
+}
+
+func TestRotateWriter_HooksPerReference(t *testing.T) {
+	dir := tempDir(t)
+	file := filepath.Join(dir, "app.log")
+
+	var a, b int
+
+	ra := openRotateWriter(file, RotationConfig{}, rotateHooks{onRotate: func(RotateEvent) { a++ }})
+	rb := openRotateWriter(file, RotationConfig{}, rotateHooks{onRotate: func(RotateEvent) { b++ }})
+
+	mustWrite(t, ra, "line\n")
+	assert.NoError(t, ra.Rotate())
+	ra.millWG.Wait()
+
+	assert.NoError(t, ra.Close())
+
+	mustWrite(t, rb, "line\n")
+	assert.NoError(t, rb.Rotate())
+	rb.millWG.Wait()
+
+	assert.NoError(t, rb.Close())
+
+	assert.Equal(t, 1, a)
+	assert.Equal(t, 2, b)
+}
+
+func TestRotateWriter_Reopen(t *testing.T) {
+	dir := tempDir(t)
+	file := filepath.Join(dir, "app.log")
+	moved := filepath.Join(dir, "app.log.1")
+
+	w := newTestRotateWriter(t, file, RotationConfig{}, newFakeClock())
+
+	mustWrite(t, w, "before\n")
+	assert.NoError(t, os.Rename(file, moved))
+
+	assert.NoError(t, w.Reopen())
+	mustWrite(t, w, "after\n")
+
+	assert.Equal(t, "before\n", readFile(t, moved))
+	assert.Equal(t, "after\n", readFile(t, file))
+	assert.Equal(t, []string{"app.log", "app.log.1"}, readDir(t, dir))
+
+	assert.NoError(t, w.Close())
+	assert.Equal(t, errWriterClosed, w.Reopen())
+}
@@ -0,0 +1,229 @@
+package log
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule reports the wall-clock boundaries at which log files are cut.
+type Schedule interface {
+	// Next returns the first boundary strictly after t, in t's location.
+	// It returns the zero time if there is none.
+	Next(t time.Time) time.Time
+}
+
+// ParseSchedule parses a rotation schedule. It accepts a standard 5-field
+// cron spec ("minute hour day-of-month month day-of-week"), one of the
+// descriptors @yearly, @annually, @monthly, @weekly, @daily, @midnight and
+// @hourly, or "@every <duration>" for fixed intervals aligned to midnight.
+func ParseSchedule(spec string) (Schedule, error) {
+	spec = strings.TrimSpace(spec)
+
+	switch strings.ToLower(spec) {
+	case "@yearly", "@annually":
+		spec = "0 0 1 1 *"
+	case "@monthly":
+		spec = "0 0 1 * *"
+	case "@weekly":
+		spec = "0 0 * * 0"
+	case "@daily", "@midnight":
+		spec = "0 0 * * *"
+	case "@hourly":
+		spec = "0 * * * *"
+	}
+
+	if strings.HasPrefix(spec, "@every ") {
+		d, err := time.ParseDuration(strings.TrimSpace(strings.TrimPrefix(spec, "@every ")))
+		if err != nil {
+			return nil, fmt.Errorf("not a valid Schedule: %q: %v", spec, err)
+		}
+
+		if d < time.Second {
+			return nil, fmt.Errorf("not a valid Schedule: %q: interval must be at least 1s", spec)
+		}
+
+		return intervalSchedule(d), nil
+	}
+
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("not a valid Schedule: %q: expected 5 fields, found %d", spec, len(fields))
+	}
+
+	s := &cronSchedule{}
+
+	for i, f := range []struct {
+		dst      *uint64
+		min, max int
+		names    []string
+	}{
+		{dst: &s.minute, min: 0, max: 59},
+		{dst: &s.hour, min: 0, max: 23},
+		{dst: &s.dom, min: 1, max: 31},
+		{dst: &s.month, min: 1, max: 12, names: monthNames},
+		{dst: &s.dow, min: 0, max: 7, names: weekdayNames},
+	} {
+		bits, err := parseCronField(fields[i], f.min, f.max, f.names)
+		if err != nil {
+			return nil, fmt.Errorf("not a valid Schedule: %q: %v", spec, err)
+		}
+
+		*f.dst = bits
+	}
+
+	// 7 is an alias for Sunday
+	if s.dow&(1<<7) != 0 {
+		s.dow |= 1
+	}
+
+	s.domStar = fields[2] == "*" || fields[2] == "?"
+	s.dowStar = fields[4] == "*" || fields[4] == "?"
+
+	return s, nil
+}
+
+var (
+	monthNames   = []string{"", "jan", "feb", "mar", "apr", "may", "jun", "jul", "aug", "sep", "oct", "nov", "dec"}
+	weekdayNames = []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+)
+
+func parseCronField(field string, min, max int, names []string) (uint64, error) {
+	var bits uint64
+
+	for _, part := range strings.Split(field, ",") {
+		step := 1
+
+		if i := strings.IndexByte(part, '/'); i >= 0 {
+			n, err := strconv.Atoi(part[i+1:])
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("invalid step in %q", part)
+			}
+
+			step = n
+			part = part[:i]
+		}
+
+		lo, hi := min, max
+
+		switch {
+		case part == "*" || part == "?":
+		case strings.IndexByte(part, '-') > 0:
+			i := strings.IndexByte(part, '-')
+
+			var err error
+			if lo, err = parseCronValue(part[:i], min, max, names); err != nil {
+				return 0, err
+			}
+			if hi, err = parseCronValue(part[i+1:], min, max, names); err != nil {
+				return 0, err
+			}
+
+			if lo > hi {
+				return 0, fmt.Errorf("invalid range %q", part)
+			}
+		default:
+			v, err := parseCronValue(part, min, max, names)
+			if err != nil {
+				return 0, err
+			}
+
+			lo = v
+			if step == 1 {
+				hi = v
+			}
+		}
+
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+
+	return bits, nil
+}
+
+func parseCronValue(s string, min, max int, names []string) (int, error) {
+	for i, name := range names {
+		if name != "" && strings.EqualFold(s, name) {
+			return i, nil
+		}
+	}
+
+	v, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value %q", s)
+	}
+
+	if v < min || v > max {
+		return 0, fmt.Errorf("value %d out of range [%d, %d]", v, min, max)
+	}
+
+	return v, nil
+}
+
+type cronSchedule struct {
+	minute, hour, dom, month, dow uint64
+
+	domStar, dowStar bool
+}
+
+func (s *cronSchedule) Next(t time.Time) time.Time {
+	loc := t.Location()
+
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+
+	for t.Before(limit) {
+		if s.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+			continue
+		}
+
+		if !s.matchDay(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+			continue
+		}
+
+		if s.hour&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc)
+			continue
+		}
+
+		if s.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Truncate(time.Minute).Add(time.Minute)
+			continue
+		}
+
+		return t
+	}
+
+	return time.Time{}
+}
+
+// matchDay follows cron: if both day fields are restricted, either may match.
+func (s *cronSchedule) matchDay(t time.Time) bool {
+	dom := s.dom&(1<<uint(t.Day())) != 0
+	dow := s.dow&(1<<uint(t.Weekday())) != 0
+
+	if s.domStar || s.dowStar {
+		return dom && dow
+	}
+
+	return dom || dow
+}
+
+type intervalSchedule time.Duration
+
+func (s intervalSchedule) Next(t time.Time) time.Time {
+	d := time.Duration(s)
+
+	// align intervals that divide a day to local midnight
+	if (24*time.Hour)%d == 0 {
+		midnight := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+
+		return midnight.Add((t.Sub(midnight)/d + 1) * d)
+	}
+
+	return t.Truncate(d).Add(d)
+}
@@ -0,0 +1,94 @@
+package log
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSchedule(t *testing.T) {
+	loc := time.FixedZone("UTC+8", 8*60*60)
+	from := time.Date(2021, 3, 5, 10, 17, 42, 0, loc) // Friday
+
+	for _, s := range []struct {
+		spec     string
+		expected time.Time
+	}{
+		{
+			spec:     "@hourly",
+			expected: time.Date(2021, 3, 5, 11, 0, 0, 0, loc),
+		},
+		{
+			spec:     "@daily",
+			expected: time.Date(2021, 3, 6, 0, 0, 0, 0, loc),
+		},
+		{
+			spec:     "@weekly",
+			expected: time.Date(2021, 3, 7, 0, 0, 0, 0, loc),
+		},
+		{
+			spec:     "@monthly",
+			expected: time.Date(2021, 4, 1, 0, 0, 0, 0, loc),
+		},
+		{
+			spec:     "@yearly",
+			expected: time.Date(2022, 1, 1, 0, 0, 0, 0, loc),
+		},
+		{
+			spec:     "*/15 * * * *",
+			expected: time.Date(2021, 3, 5, 10, 30, 0, 0, loc),
+		},
+		{
+			spec:     "30 2 * * mon-fri",
+			expected: time.Date(2021, 3, 8, 2, 30, 0, 0, loc),
+		},
+		{
+			spec:     "0 0 13 * 5",
+			expected: time.Date(2021, 3, 12, 0, 0, 0, 0, loc),
+		},
+		{
+			spec:     "0 12 1,15 feb,mar *",
+			expected: time.Date(2021, 3, 15, 12, 0, 0, 0, loc),
+		},
+		{
+			spec:     "0 0 * * 7",
+			expected: time.Date(2021, 3, 7, 0, 0, 0, 0, loc),
+		},
+		{
+			spec:     "@every 6h",
+			expected: time.Date(2021, 3, 5, 12, 0, 0, 0, loc),
+		},
+		{
+			spec:     "@every 7m",
+			expected: from.Truncate(7 * time.Minute).Add(7 * time.Minute),
+		},
+	} {
+		schedule, err := ParseSchedule(s.spec)
+		if assert.NoError(t, err, s.spec) {
+			assert.Equal(t, s.expected, schedule.Next(from), s.spec)
+		}
+	}
+
+	for _, spec := range []string{
+		"",
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"5-1 * * * *",
+		"*/0 * * * *",
+		"* * * foo *",
+		"@every",
+		"@every 1ms",
+	} {
+		_, err := ParseSchedule(spec)
+		assert.Error(t, err, spec)
+	}
+}
+
+func TestParseSchedule_Never(t *testing.T) {
+	schedule, err := ParseSchedule("0 0 31 2 *")
+	assert.NoError(t, err)
+	assert.True(t, schedule.Next(time.Now()).IsZero())
+}
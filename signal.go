@@ -0,0 +1,76 @@
+package log
+
+import (
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+)
+
+// SignalAction is what HandleSignals does with the log files.
+type SignalAction int
+
+const (
+	// ReopenOnSignal reopens the files, for an external rotation such as
+	// logrotate with "postrotate kill -HUP".
+	ReopenOnSignal SignalAction = iota
+	// RotateOnSignal rotates the files.
+	RotateOnSignal
+)
+
+// HandleSignals reopens or rotates every log file of l whenever one of
+// sigs is received, SIGHUP if none is given. A nil l stands for the global
+// logger at the time of the signal. Call stop to uninstall the handler.
+func HandleSignals(l *Logger, action SignalAction, sigs ...os.Signal) (stop func()) {
+	if len(sigs) == 0 {
+		sigs = []os.Signal{syscall.SIGHUP}
+	}
+
+	ch := make(chan os.Signal, 1)
+	done := make(chan struct{})
+
+	var wg sync.WaitGroup
+
+	signal.Notify(ch, sigs...)
+
+	wg.Add(1)
+
+	go func() {
+		defer wg.Done()
+
+		for {
+			select {
+			case <-ch:
+				target := l
+				if target == nil {
+					target = loadGlobal()
+				}
+
+				var err error
+
+				switch action {
+				case RotateOnSignal:
+					err = target.Rotate()
+				default:
+					err = target.Reopen()
+				}
+
+				if err != nil {
+					reportError(err)
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	var once sync.Once
+
+	return func() {
+		once.Do(func() {
+			signal.Stop(ch)
+			close(done)
+			wg.Wait()
+		})
+	}
+}
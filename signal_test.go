@@ -0,0 +1,105 @@
+//go:build !windows && !plan9
+// +build !windows,!plan9
+
+package log
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out")
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestHandleSignals_Reopen(t *testing.T) {
+	dir := tempDir(t)
+	file := filepath.Join(dir, "app.log")
+	moved := filepath.Join(dir, "app.log.1")
+
+	l := New(WithLogToStdout(false), WithLogFiles(file))
+	defer l.Close()
+
+	stop := HandleSignals(l, ReopenOnSignal)
+	defer stop()
+
+	l.Info("before")
+
+	// what logrotate does before "postrotate kill -HUP"
+	assert.NoError(t, os.Rename(file, moved))
+	assert.NoError(t, syscall.Kill(os.Getpid(), syscall.SIGHUP))
+
+	waitFor(t, func() bool {
+		_, err := os.Stat(file)
+		return err == nil
+	})
+
+	l.Info("after")
+
+	assert.Contains(t, readFile(t, moved), "before")
+	assert.NotContains(t, readFile(t, moved), "after")
+	assert.Contains(t, readFile(t, file), "after")
+}
+
+func TestHandleSignals_Rotate(t *testing.T) {
+	dir := tempDir(t)
+	file := filepath.Join(dir, "app.log")
+
+	rotated := make(chan RotateEvent, 1)
+
+	l := New(WithLogToStdout(false), WithLogFiles(file), OnRotate(func(e RotateEvent) {
+		rotated <- e
+	}))
+	defer l.Close()
+
+	stop := HandleSignals(l, RotateOnSignal, syscall.SIGUSR1)
+
+	l.Info("before")
+	assert.NoError(t, syscall.Kill(os.Getpid(), syscall.SIGUSR1))
+
+	select {
+	case e := <-rotated:
+		assert.Contains(t, readFile(t, e.Backup), "before")
+	case <-time.After(5 * time.Second):
+		t.Fatal("not rotated")
+	}
+
+	stop()
+	stop()
+}
+
+func TestHandleSignals_Global(t *testing.T) {
+	dir := tempDir(t)
+	file := filepath.Join(dir, "app.log")
+	moved := filepath.Join(dir, "app.log.1")
+
+	defer ReplaceGlobal(New(WithLogToStdout(false), WithLogFiles(file)))()
+
+	stop := HandleSignals(nil, ReopenOnSignal, syscall.SIGUSR2)
+	defer stop()
+
+	Info("before")
+
+	assert.NoError(t, os.Rename(file, moved))
+	assert.NoError(t, syscall.Kill(os.Getpid(), syscall.SIGUSR2))
+
+	waitFor(t, func() bool {
+		_, err := os.Stat(file)
+		return err == nil
+	})
+}
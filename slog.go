@@ -0,0 +1,314 @@
+package log
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"runtime"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// slogHandler is a slog.Handler that writes through the cores of a Logger.
+type slogHandler struct {
+	core      zapcore.Core
+	name      string
+	addCaller bool
+
+	// groups are opened by WithGroup and only added to the entry with the
+	// first attribute below them, empty groups are omitted.
+	groups []string
+}
+
+// NewSlogHandler returns a slog.Handler that writes to the outputs of l and
+// follows its levels. A nil l stands for the global logger at the time of
+// each entry.
+//
+// The slog levels Debug, Info, Warn and Error are DebugLevel to
+// ErrorLevel, levels in between round down to the next lower one, levels
+// above Error are ErrorLevel. Groups become namespaces of the entry.
+// Records with a zero time are written without one, except by encoders
+// set with WithEncoder.
+func NewSlogHandler(l *Logger) slog.Handler {
+	if l == nil {
+		return &slogHandler{
+			core:      &globalCore{},
+			addCaller: true,
+		}
+	}
+
+	return &slogHandler{
+		core:      l.zap.Core(),
+		name:      l.name,
+		addCaller: l.options.AddCaller,
+	}
+}
+
+func (h *slogHandler) Enabled(_ context.Context, lvl slog.Level) bool {
+	return h.core.Enabled(fromSlogLevel(lvl))
+}
+
+func (h *slogHandler) Handle(ctx context.Context, r slog.Record) error {
+	ent := zapcore.Entry{
+		LoggerName: h.name,
+		Time:       r.Time,
+		Level:      fromSlogLevel(r.Level),
+		Message:    r.Message,
+	}
+
+	if h.addCaller && r.PC != 0 {
+		frame, _ := runtime.CallersFrames([]uintptr{r.PC}).Next()
+		ent.Caller = zapcore.EntryCaller{
+			Defined:  true,
+			PC:       frame.PC,
+			File:     frame.File,
+			Line:     frame.Line,
+			Function: frame.Function,
+		}
+	}
+
+	ce := h.core.Check(ent, nil)
+	if ce == nil {
+		return nil
+	}
+
+	fields := keysAndValuesFields(contextKeysAndValues(ctx, nil))
+
+	var attrs []zapcore.Field
+
+	r.Attrs(func(a slog.Attr) bool {
+		attrs = appendAttr(attrs, a)
+		return true
+	})
+
+	if len(attrs) > 0 {
+		for _, g := range h.groups {
+			fields = append(fields, zap.Namespace(g))
+		}
+
+		fields = append(fields, attrs...)
+	}
+
+	ce.Write(fields...)
+
+	return nil
+}
+
+func (h *slogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	var fields []zapcore.Field
+
+	for _, g := range h.groups {
+		fields = append(fields, zap.Namespace(g))
+	}
+
+	n := len(fields)
+
+	for _, a := range attrs {
+		fields = appendAttr(fields, a)
+	}
+
+	if len(fields) == n {
+		return h
+	}
+
+	c := *h
+	c.core = h.core.With(fields)
+	c.groups = nil
+
+	return &c
+}
+
+func (h *slogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+
+	c := *h
+	c.groups = append(h.groups[:len(h.groups):len(h.groups)], name)
+
+	return &c
+}
+
+// appendAttr appends a as a field, following the rules of slog.Handler for
+// empty attributes and groups.
+func appendAttr(fields []zapcore.Field, a slog.Attr) []zapcore.Field {
+	a.Value = a.Value.Resolve()
+
+	if a.Equal(slog.Attr{}) {
+		return fields
+	}
+
+	switch a.Value.Kind() {
+	case slog.KindGroup:
+		attrs := a.Value.Group()
+		if len(attrs) == 0 {
+			return fields
+		}
+
+		if a.Key == "" {
+			for _, ga := range attrs {
+				fields = appendAttr(fields, ga)
+			}
+
+			return fields
+		}
+
+		return append(fields, zap.Object(a.Key, slogGroup(attrs)))
+	case slog.KindBool:
+		return append(fields, zap.Bool(a.Key, a.Value.Bool()))
+	case slog.KindDuration:
+		return append(fields, zap.Duration(a.Key, a.Value.Duration()))
+	case slog.KindFloat64:
+		return append(fields, zap.Float64(a.Key, a.Value.Float64()))
+	case slog.KindInt64:
+		return append(fields, zap.Int64(a.Key, a.Value.Int64()))
+	case slog.KindString:
+		return append(fields, zap.String(a.Key, a.Value.String()))
+	case slog.KindTime:
+		return append(fields, zap.Time(a.Key, a.Value.Time()))
+	case slog.KindUint64:
+		return append(fields, zap.Uint64(a.Key, a.Value.Uint64()))
+	}
+
+	return append(fields, zap.Any(a.Key, a.Value.Any()))
+}
+
+type slogGroup []slog.Attr
+
+func (g slogGroup) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	var fields []zapcore.Field
+
+	for _, a := range g {
+		fields = appendAttr(fields, a)
+	}
+
+	for _, f := range fields {
+		f.AddTo(enc)
+	}
+
+	return nil
+}
+
+func fromSlogLevel(lvl slog.Level) zapcore.Level {
+	switch {
+	case lvl < slog.LevelInfo:
+		return zapcore.DebugLevel
+	case lvl < slog.LevelWarn:
+		return zapcore.InfoLevel
+	case lvl < slog.LevelError:
+		return zapcore.WarnLevel
+	}
+
+	return zapcore.ErrorLevel
+}
+
+// toSlogLevel maps DebugLevel to ErrorLevel to the slog levels Debug to
+// Error, the levels above are Error+4, Error+8 and so on.
+func toSlogLevel(lvl zapcore.Level) slog.Level {
+	return slog.Level(4 * int(lvl))
+}
+
+// slogCore sends the entries of a logger to a slog.Handler.
+type slogCore struct {
+	zapcore.LevelEnabler
+
+	handler  slog.Handler
+	nameKey  string
+	stackKey string
+
+	// fields are added by With, they are kept apart from the handler so
+	// that their namespaces do not cover the logger name
+	fields []zapcore.Field
+}
+
+func (c *slogCore) Enabled(lvl zapcore.Level) bool {
+	return c.LevelEnabler.Enabled(lvl) && c.handler.Enabled(context.Background(), toSlogLevel(lvl))
+}
+
+func (c *slogCore) With(fields []zapcore.Field) zapcore.Core {
+	clone := *c
+	clone.fields = append(c.fields[:len(c.fields):len(c.fields)], fields...)
+
+	return &clone
+}
+
+func (c *slogCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+
+	return ce
+}
+
+func (c *slogCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	var pc uintptr
+	if ent.Caller.Defined {
+		pc = ent.Caller.PC
+	}
+
+	r := slog.NewRecord(ent.Time, toSlogLevel(ent.Level), ent.Message, pc)
+
+	if ent.LoggerName != "" && c.nameKey != "" {
+		r.AddAttrs(slog.String(c.nameKey, ent.LoggerName))
+	}
+
+	if ent.Stack != "" && c.stackKey != "" {
+		r.AddAttrs(slog.String(c.stackKey, ent.Stack))
+	}
+
+	if len(c.fields) > 0 {
+		fields = append(c.fields[:len(c.fields):len(c.fields)], fields...)
+	}
+
+	r.AddAttrs(fieldAttrs(fields)...)
+
+	return c.handler.Handle(context.Background(), r)
+}
+
+func (c *slogCore) Sync() error {
+	return nil
+}
+
+// fieldAttrs turns fields into attributes, the fields after a namespace
+// are grouped below it.
+func fieldAttrs(fields []zapcore.Field) []slog.Attr {
+	attrs := make([]slog.Attr, 0, len(fields))
+
+	for i, f := range fields {
+		if f.Type == zapcore.NamespaceType {
+			return append(attrs, slog.Attr{Key: f.Key, Value: slog.GroupValue(fieldAttrs(fields[i+1:])...)})
+		}
+
+		if f.Type == zapcore.SkipType {
+			continue
+		}
+
+		attrs = append(attrs, fieldAttr(f))
+	}
+
+	return attrs
+}
+
+func fieldAttr(f zapcore.Field) slog.Attr {
+	switch f.Type {
+	case zapcore.BoolType:
+		return slog.Bool(f.Key, f.Integer == 1)
+	case zapcore.DurationType:
+		return slog.Duration(f.Key, time.Duration(f.Integer))
+	case zapcore.Int64Type, zapcore.Int32Type, zapcore.Int16Type, zapcore.Int8Type:
+		return slog.Int64(f.Key, f.Integer)
+	case zapcore.StringType:
+		return slog.String(f.Key, f.String)
+	case zapcore.ErrorType:
+		return slog.Any(f.Key, f.Interface)
+	case zapcore.StringerType:
+		return slog.String(f.Key, fmt.Sprint(f.Interface))
+	}
+
+	enc := zapcore.NewMapObjectEncoder()
+	f.AddTo(enc)
+
+	return slog.Any(f.Key, enc.Fields[f.Key])
+}
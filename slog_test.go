@@ -0,0 +1,137 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"path/filepath"
+	"testing"
+	"testing/slogtest"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestSlogHandler(t *testing.T) {
+	var buf bytes.Buffer
+
+	l := New(WithLogToStdout(false), WithOutput(&buf), AddCaller())
+	s := slog.New(NewSlogHandler(l.Named("api")))
+
+	s.Info("request", "status", 200, slog.Group("user", "id", 7))
+	assert.Contains(t, buf.String(), `"level":"info"`)
+	assert.Contains(t, buf.String(), `"logger":"api","caller":`)
+	assert.Contains(t, buf.String(), `/slog_test.go:`)
+	assert.Contains(t, buf.String(), `"msg":"request","status":200,"user":{"id":7}`)
+
+	buf.Reset()
+	s.With("a", 1).WithGroup("req").With("b", 2).WithGroup("empty").Warn("grouped", "c", 3)
+	assert.Contains(t, buf.String(), `"level":"warn"`)
+	assert.Contains(t, buf.String(), `"msg":"grouped","a":1,"req":{"b":2,"empty":{"c":3}}`)
+
+	// empty groups are omitted
+	buf.Reset()
+	s.WithGroup("empty").Error("no attrs", slog.Group("none"))
+	assert.Contains(t, buf.String(), `"msg":"no attrs"}`)
+
+	// levels follow the logger
+	buf.Reset()
+	s.Debug("dropped")
+	s.Log(context.Background(), slog.LevelDebug+2, "dropped")
+	s.Log(context.Background(), slog.LevelInfo+2, "custom")
+	assert.NotContains(t, buf.String(), "dropped")
+	assert.Contains(t, buf.String(), `"level":"info"`)
+
+	l.SetLevel(ErrorLevel)
+	assert.False(t, s.Enabled(context.Background(), slog.LevelWarn))
+	assert.True(t, s.Enabled(context.Background(), slog.LevelError+4))
+}
+
+func TestSlogHandler_Global(t *testing.T) {
+	defer ReplaceGlobal(New(WithLogToStdout(false)))()
+
+	s := slog.New(NewSlogHandler(nil)).With("a", 1)
+
+	file := filepath.Join(tempDir(t), "global.log")
+	SetOptions(WithLogFiles(file), AddCaller())
+
+	s.Debug("dropped")
+	s.Info("request", "status", 200)
+	assert.NoError(t, Sync())
+
+	content := readFile(t, file)
+	assert.NotContains(t, content, "dropped")
+	assert.Contains(t, content, `/slog_test.go:`)
+	assert.Contains(t, content, `"msg":"request","a":1,"status":200}`)
+}
+
+func TestSlogLevels(t *testing.T) {
+	for _, tt := range []struct {
+		slog slog.Level
+		zap  zapcore.Level
+	}{
+		{slog.LevelDebug - 4, zapcore.DebugLevel},
+		{slog.LevelDebug, zapcore.DebugLevel},
+		{slog.LevelInfo - 1, zapcore.DebugLevel},
+		{slog.LevelInfo, zapcore.InfoLevel},
+		{slog.LevelWarn - 1, zapcore.InfoLevel},
+		{slog.LevelWarn, zapcore.WarnLevel},
+		{slog.LevelError, zapcore.ErrorLevel},
+		{slog.LevelError + 8, zapcore.ErrorLevel},
+	} {
+		assert.Equal(t, tt.zap, fromSlogLevel(tt.slog), tt.slog.String())
+	}
+
+	assert.Equal(t, slog.LevelDebug, toSlogLevel(zapcore.DebugLevel))
+	assert.Equal(t, slog.LevelError, toSlogLevel(zapcore.ErrorLevel))
+	assert.Equal(t, slog.LevelError+4, toSlogLevel(zapcore.DPanicLevel))
+}
+
+func TestWithSlogHandler(t *testing.T) {
+	var buf bytes.Buffer
+
+	h := slog.NewTextHandler(&buf, &slog.HandlerOptions{
+		Level: slog.LevelDebug,
+		ReplaceAttr: func(_ []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+
+			return a
+		},
+	})
+
+	l := New(WithLogToStdout(false), WithSlogHandler(h), WithTargetLevel(SlogTarget, WarnLevel))
+
+	l.Info("dropped")
+	l.Named("db").With("shard", 3, zap.Namespace("query")).Warnw("slow", "rows", 10, "err", errors.New("timeout"))
+	l.Errorw("nested", zap.Namespace("outer"), "a", 1)
+
+	assert.Equal(t, "level=WARN msg=slow logger=db shard=3 query.rows=10 query.err=timeout\n"+
+		"level=ERROR msg=nested outer.a=1\n", buf.String())
+}
+
+func TestSlogHandler_slogtest(t *testing.T) {
+	var buf bytes.Buffer
+
+	l := New(WithLogToStdout(false), WithOutput(&buf), WithFormat(FormatJSON), WithLevel(DebugLevel))
+
+	err := slogtest.TestHandler(NewSlogHandler(l), func() []map[string]any {
+		var entries []map[string]any
+
+		for _, line := range bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n")) {
+			var m map[string]any
+			if err := json.Unmarshal(line, &m); err != nil {
+				t.Fatal(err)
+			}
+
+			entries = append(entries, m)
+		}
+
+		return entries
+	})
+	assert.NoError(t, err)
+}
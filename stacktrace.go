@@ -0,0 +1,185 @@
+package log
+
+import (
+	"runtime"
+	"strconv"
+	"strings"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// StacktraceConfig captures a stack trace with the entries at Level and
+// above. Without it no stack traces are captured.
+type StacktraceConfig struct {
+	Level Level `json:"level"`
+
+	// TrimRuntime drops the frames of the Go runtime.
+	TrimRuntime bool `json:"trimRuntime"`
+	// TrimLogger drops the frames of this package, its subpackages and zap.
+	TrimLogger bool `json:"trimLogger"`
+	// MaxDepth limits the number of frames after trimming, 0 keeps all.
+	MaxDepth int `json:"maxDepth"`
+	// Structured writes the stack as an array of frames with function,
+	// file and line, instead of a single string.
+	Structured bool `json:"structured"`
+}
+
+func (c StacktraceConfig) apply(o *options) {
+	o.Stacktrace = &c
+}
+
+// AddStacktrace captures a stack trace with the entries at lvl and above.
+// The other settings of a StacktraceConfig given before are kept, without
+// one the frames of this package are trimmed.
+func AddStacktrace(lvl Level) Option {
+	return optionFunc(func(o *options) {
+		c := StacktraceConfig{TrimLogger: true}
+		if o.Stacktrace != nil {
+			c = *o.Stacktrace
+		}
+
+		c.Level = lvl
+		o.Stacktrace = &c
+	})
+}
+
+// maxStackFrames bounds the frames looked at for one stack trace.
+const maxStackFrames = 64
+
+var loggerFramePrefixes = []string{
+	"go.kuoruan.net/log.",
+	"go.kuoruan.net/log/",
+	"go.uber.org/zap.",
+	"go.uber.org/zap/",
+}
+
+// stacktraceCore adds a stack trace to the entries it lets through.
+type stacktraceCore struct {
+	zapcore.Core
+
+	config StacktraceConfig
+	key    string
+}
+
+func newStacktraceCore(core zapcore.Core, config StacktraceConfig, key string) zapcore.Core {
+	if key == "" {
+		key = "stacktrace"
+	}
+
+	return &stacktraceCore{
+		Core:   core,
+		config: config,
+		key:    key,
+	}
+}
+
+func (c *stacktraceCore) With(fields []zapcore.Field) zapcore.Core {
+	return &stacktraceCore{
+		Core:   c.Core.With(fields),
+		config: c.config,
+		key:    c.key,
+	}
+}
+
+func (c *stacktraceCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if !c.config.Level.Enabled(fromZapLevel(ent.Level)) || !c.Core.Enabled(ent.Level) {
+		return c.Core.Check(ent, ce)
+	}
+
+	frames := c.frames()
+
+	if c.config.Structured {
+		// the stack is added as a field to the cores that accept the entry
+		return c.Core.With([]zapcore.Field{zap.Array(c.key, frames)}).Check(ent, ce)
+	}
+
+	ent.Stack = frames.String()
+
+	return c.Core.Check(ent, ce)
+}
+
+// frames captures the stack of the caller of Check.
+func (c *stacktraceCore) frames() stackFrames {
+	pcs := make([]uintptr, maxStackFrames)
+	// skip runtime.Callers, frames and Check
+	pcs = pcs[:runtime.Callers(3, pcs)]
+
+	var frames stackFrames
+
+	it := runtime.CallersFrames(pcs)
+	for {
+		frame, more := it.Next()
+
+		if !c.trim(frame.Function) {
+			frames = append(frames, frame)
+
+			if c.config.MaxDepth > 0 && len(frames) == c.config.MaxDepth {
+				break
+			}
+		}
+
+		if !more {
+			break
+		}
+	}
+
+	return frames
+}
+
+func (c *stacktraceCore) trim(function string) bool {
+	if c.config.TrimRuntime && strings.HasPrefix(function, "runtime.") {
+		return true
+	}
+
+	if c.config.TrimLogger {
+		for _, prefix := range loggerFramePrefixes {
+			if strings.HasPrefix(function, prefix) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+type stackFrames []runtime.Frame
+
+// String formats the frames like the stack traces of zap.
+func (s stackFrames) String() string {
+	var b strings.Builder
+
+	for i, f := range s {
+		if i > 0 {
+			b.WriteByte('\n')
+		}
+
+		b.WriteString(f.Function)
+		b.WriteString("\n\t")
+		b.WriteString(f.File)
+		b.WriteByte(':')
+		b.WriteString(strconv.Itoa(f.Line))
+	}
+
+	return b.String()
+}
+
+func (s stackFrames) MarshalLogArray(enc zapcore.ArrayEncoder) error {
+	for _, f := range s {
+		if err := enc.AppendObject(stackFrame(f)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+type stackFrame runtime.Frame
+
+func (f stackFrame) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddString("function", f.Function)
+	enc.AddString("file", f.File)
+	enc.AddInt("line", f.Line)
+
+	return nil
+}
@@ -0,0 +1,83 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAddStacktrace(t *testing.T) {
+	var buf bytes.Buffer
+
+	l := New(WithLogToStdout(false), WithOutput(&buf), AddStacktrace(WarnLevel))
+
+	l.Info("no stack")
+	assert.NotContains(t, buf.String(), "stacktrace")
+
+	buf.Reset()
+	l.Warn("stack")
+
+	var entry map[string]interface{}
+	assert.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+
+	stack, _ := entry["stacktrace"].(string)
+	assert.Contains(t, stack, "testing.tRunner")
+	// the test itself is in this package
+	assert.NotContains(t, stack, "go.kuoruan.net/log.")
+	assert.NotContains(t, stack, "go.uber.org/zap")
+}
+
+func TestStacktraceConfig(t *testing.T) {
+	var buf bytes.Buffer
+
+	l := New(WithLogToStdout(false), WithOutput(&buf), StacktraceConfig{Level: ErrorLevel})
+	l.Error("stack")
+
+	var entry map[string]interface{}
+	assert.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+
+	stack, _ := entry["stacktrace"].(string)
+	assert.Contains(t, stack, "go.kuoruan.net/log.TestStacktraceConfig")
+	assert.Contains(t, stack, "/stacktrace_test.go:")
+	assert.Contains(t, stack, "runtime.goexit")
+
+	buf.Reset()
+	l = New(WithLogToStdout(false), WithOutput(&buf),
+		StacktraceConfig{Level: ErrorLevel, TrimRuntime: true, MaxDepth: 2})
+	l.Error("stack")
+
+	entry = nil
+	assert.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+
+	stack, _ = entry["stacktrace"].(string)
+	assert.NotContains(t, stack, "runtime.")
+	assert.Equal(t, 2, strings.Count(stack, "\n\t"))
+}
+
+func TestStacktraceConfig_Structured(t *testing.T) {
+	var buf bytes.Buffer
+
+	l := New(WithLogToStdout(false), WithOutput(&buf),
+		StacktraceConfig{Level: ErrorLevel, TrimLogger: true, Structured: true}).With("k", "v")
+	l.Error("stack")
+
+	var entry struct {
+		K          string
+		Stacktrace []struct {
+			Function string
+			File     string
+			Line     int
+		}
+	}
+	assert.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+
+	assert.Equal(t, "v", entry.K)
+	if assert.NotEmpty(t, entry.Stacktrace) {
+		assert.Equal(t, "testing.tRunner", entry.Stacktrace[0].Function)
+		assert.NotEmpty(t, entry.Stacktrace[0].File)
+		assert.NotZero(t, entry.Stacktrace[0].Line)
+	}
+}
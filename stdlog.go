@@ -0,0 +1,39 @@
+package log
+
+import (
+	stdlog "log"
+
+	"go.uber.org/zap"
+)
+
+// StdLogger returns a standard library logger that writes to l at level,
+// e.g. for http.Server.ErrorLog. The entries have no stdlib prefix or
+// trailing newline and report the caller of the stdlib logger.
+func (l *Logger) StdLogger(level Level) *stdlog.Logger {
+	// every Level of this package is valid for zap
+	std, _ := zap.NewStdLogAt(l.stdZapLogger(), toZapLevel(level))
+
+	return std
+}
+
+// RedirectStdLog sends the output of the standard library log package to
+// l at level, a nil l stands for the global logger at the time of each
+// entry. The flags and prefix of the stdlib logger are cleared until
+// restore is called.
+func RedirectStdLog(l *Logger, level Level) (restore func()) {
+	logger := zap.New(&globalCore{}, zap.AddCaller())
+	if l != nil {
+		logger = l.stdZapLogger()
+	}
+
+	// every Level of this package is valid for zap
+	restore, _ = zap.RedirectStdLogAt(logger, toZapLevel(level))
+
+	return restore
+}
+
+// stdZapLogger returns the logger without the caller skip of the methods
+// of Logger, zap adds the frames of the stdlib logger itself.
+func (l *Logger) stdZapLogger() *zap.Logger {
+	return l.zap.WithOptions(zap.AddCallerSkip(-l.options.CallerSkip))
+}
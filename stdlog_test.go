@@ -0,0 +1,66 @@
+package log
+
+import (
+	"bytes"
+	stdlog "log"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLogger_StdLogger(t *testing.T) {
+	var buf bytes.Buffer
+
+	l := New(WithLogToStdout(false), WithOutput(&buf), AddCaller())
+	l.StdLogger(WarnLevel).Printf("stdlib %d\n", 1)
+
+	assert.Contains(t, buf.String(), `"level":"warn"`)
+	assert.Contains(t, buf.String(), `/stdlog_test.go:`)
+	assert.Contains(t, buf.String(), `"msg":"stdlib 1"}`)
+}
+
+func TestRedirectStdLog(t *testing.T) {
+	var buf bytes.Buffer
+
+	flags, prefix, writer := stdlog.Flags(), stdlog.Prefix(), stdlog.Writer()
+	stdlog.SetPrefix("app: ")
+
+	l := New(WithLogToStdout(false), WithOutput(&buf), AddCaller())
+	restore := RedirectStdLog(l, ErrorLevel)
+
+	stdlog.Println("redirected")
+
+	assert.Contains(t, buf.String(), `"level":"error"`)
+	assert.Contains(t, buf.String(), `/stdlog_test.go:`)
+	assert.Contains(t, buf.String(), `"msg":"redirected"}`)
+
+	restore()
+	assert.Equal(t, flags, stdlog.Flags())
+	assert.Equal(t, "app: ", stdlog.Prefix())
+	assert.Equal(t, writer, stdlog.Writer())
+
+	stdlog.SetPrefix(prefix)
+}
+
+func TestRedirectStdLog_Global(t *testing.T) {
+	defer ReplaceGlobal(New(WithLogToStdout(false)))()
+
+	defer RedirectStdLog(nil, WarnLevel)()
+
+	file := filepath.Join(tempDir(t), "global.log")
+	SetOptions(WithLogFiles(file))
+
+	stdlog.Println("without caller")
+
+	SetOptions(AddCaller())
+
+	stdlog.Println("with caller")
+	assert.NoError(t, Sync())
+
+	content := readFile(t, file)
+	assert.Contains(t, content, `"level":"warn","time":`)
+	assert.Contains(t, content, `"msg":"without caller"}`)
+	assert.Contains(t, content, `/stdlog_test.go:`)
+	assert.Contains(t, content, `"msg":"with caller"}`)
+}
@@ -0,0 +1,54 @@
+package log
+
+import (
+	"path/filepath"
+
+	"go.uber.org/zap/zapcore"
+)
+
+type targetKind int
+
+const (
+	targetStdout targetKind = iota
+	targetStderr
+	targetOutput
+	targetFile
+	targetDir
+	targetSlog
+)
+
+// Target identifies a single output of a logger, to configure it apart
+// from the others.
+type Target struct {
+	kind targetKind
+	path string
+}
+
+var (
+	// StdoutTarget is the output enabled by LogToStdout.
+	StdoutTarget = Target{kind: targetStdout}
+	// StderrTarget is the output enabled by LogToStderr.
+	StderrTarget = Target{kind: targetStderr}
+	// OutputTarget is the writer set by WithOutput.
+	OutputTarget = Target{kind: targetOutput}
+	// SlogTarget is the handler set by WithSlogHandler.
+	SlogTarget = Target{kind: targetSlog}
+)
+
+// FileTarget is a file set by WithLogFiles.
+func FileTarget(file string) Target {
+	return Target{kind: targetFile, path: filepath.Clean(file)}
+}
+
+// DirTarget is a directory set by WithLogDirs, with all its level files.
+func DirTarget(dir string) Target {
+	return Target{kind: targetDir, path: filepath.Clean(dir)}
+}
+
+// targetOptions are the options of a single output, nil fields fall back
+// to the options of the logger.
+type targetOptions struct {
+	Level         *Level
+	Format        *Format
+	EncoderConfig *zapcore.EncoderConfig
+}
@@ -0,0 +1,132 @@
+package log
+
+import (
+	"flag"
+	"strconv"
+	"sync/atomic"
+)
+
+// Verbose writes glog-style verbose entries at InfoLevel, see Logger.V.
+// The zero Verbose is disabled and drops all entries.
+type Verbose struct {
+	l *Logger
+
+	// global writes through the global logger at the time of each entry,
+	// for Verbose returned by the package-level V.
+	global bool
+}
+
+// V returns a Verbose that writes if level is at most the verbosity of l,
+// for code migrating from glog or klog:
+//
+//	l.V(2).Infof("cache miss for %q", key)
+//
+// The entries are still subject to the level of l.
+func (l *Logger) V(level int) Verbose {
+	if int32(level) > atomic.LoadInt32(&l.verbosity) {
+		return Verbose{}
+	}
+
+	return Verbose{l: l}
+}
+
+// SetVerbosity changes the verbosity of l at runtime, like the -v flag of
+// glog.
+func (l *Logger) SetVerbosity(v int) {
+	atomic.StoreInt32(&l.verbosity, int32(v))
+}
+
+func (l *Logger) Verbosity() int {
+	return int(atomic.LoadInt32(&l.verbosity))
+}
+
+// logger returns the logger v writes through, nil if it is disabled. The
+// global logger is kept from being closed until release is called.
+func (v Verbose) logger() (l *Logger, release func()) {
+	if !v.global {
+		return v.l, func() {}
+	}
+
+	g := acquireGlobal()
+
+	return g.Logger, g.release
+}
+
+// Enabled reports whether v writes entries.
+func (v Verbose) Enabled() bool {
+	l, release := v.logger()
+	defer release()
+
+	return l != nil && l.Enabled(InfoLevel)
+}
+
+func (v Verbose) Info(args ...interface{}) {
+	l, release := v.logger()
+	defer release()
+
+	if l != nil {
+		l.info(l.base, args...)
+	}
+}
+
+func (v Verbose) Infof(format string, args ...interface{}) {
+	l, release := v.logger()
+	defer release()
+
+	if l != nil {
+		l.infof(l.base, format, args...)
+	}
+}
+
+func (v Verbose) Infoln(args ...interface{}) {
+	l, release := v.logger()
+	defer release()
+
+	if l != nil {
+		l.info(l.base, sprintln(args...))
+	}
+}
+
+func (v Verbose) Infow(msg string, keysAndValues ...interface{}) {
+	l, release := v.logger()
+	defer release()
+
+	if l != nil {
+		l.infow(l.base, msg, keysAndValues...)
+	}
+}
+
+type verbosityFlag struct {
+	l *Logger
+}
+
+// VerbosityFlag returns a flag.Value for the verbosity of l, a nil l
+// stands for the global logger at the time the flag is set:
+//
+//	flag.Var(log.VerbosityFlag(nil), "v", "log verbosity")
+func VerbosityFlag(l *Logger) flag.Value {
+	return verbosityFlag{l: l}
+}
+
+func (f verbosityFlag) logger() *Logger {
+	if f.l != nil {
+		return f.l
+	}
+
+	return loadGlobal()
+}
+
+func (f verbosityFlag) String() string {
+	return strconv.Itoa(f.logger().Verbosity())
+}
+
+func (f verbosityFlag) Set(s string) error {
+	v, err := strconv.Atoi(s)
+	if err != nil {
+		return err
+	}
+
+	f.logger().SetVerbosity(v)
+
+	return nil
+}
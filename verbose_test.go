@@ -0,0 +1,92 @@
+package log
+
+import (
+	"bytes"
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLogger_V(t *testing.T) {
+	var buf bytes.Buffer
+
+	l := New(WithLogToStdout(false), WithOutput(&buf), WithVerbosity(1), AddCaller())
+
+	assert.True(t, l.V(0).Enabled())
+	assert.True(t, l.V(1).Enabled())
+	assert.False(t, l.V(2).Enabled())
+
+	l.V(1).Infof("shown %d", 1)
+	l.V(2).Infow("dropped")
+	assert.Contains(t, buf.String(), `"level":"info"`)
+	assert.Contains(t, buf.String(), `/verbose_test.go:`)
+	assert.Contains(t, buf.String(), `"msg":"shown 1"`)
+	assert.NotContains(t, buf.String(), "dropped")
+
+	l.SetVerbosity(2)
+	l.V(2).Infoln("now", "shown")
+	assert.Contains(t, buf.String(), `"msg":"now shown"`)
+
+	// still subject to the level
+	l.SetLevel(WarnLevel)
+	assert.False(t, l.V(0).Enabled())
+
+	var zero Verbose
+	zero.Info("dropped")
+	assert.False(t, zero.Enabled())
+}
+
+func TestVerbosityFlag(t *testing.T) {
+	l := New(WithLogToStdout(false))
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(ioutil.Discard)
+	fs.Var(VerbosityFlag(l), "v", "")
+
+	assert.NoError(t, fs.Parse([]string{"-v", "3"}))
+	assert.Equal(t, 3, l.Verbosity())
+	assert.Equal(t, "3", fs.Lookup("v").Value.String())
+
+	assert.Error(t, fs.Parse([]string{"-v", "high"}))
+}
+
+func TestEnabled(t *testing.T) {
+	restore := ReplaceGlobal(New(WithLogToStdout(false), WithOutput(ioutil.Discard), WithLevel(WarnLevel)))
+	defer restore()
+
+	assert.False(t, Enabled(InfoLevel))
+	assert.True(t, Enabled(WarnLevel))
+
+	SetVerbosity(1)
+	assert.False(t, V(1).Enabled())
+
+	SetLevel(InfoLevel)
+	assert.True(t, V(1).Enabled())
+}
+
+func TestV(t *testing.T) {
+	var buf bytes.Buffer
+
+	defer ReplaceGlobal(New(WithLogToStdout(false), WithOutput(&buf), WithVerbosity(1), AddCaller()))()
+
+	v := V(1)
+	assert.True(t, v.Enabled())
+	assert.False(t, V(2).Enabled())
+
+	v.Infof("shown %d", 1)
+	V(2).Info("dropped")
+	assert.Contains(t, buf.String(), `/verbose_test.go:`)
+	assert.Contains(t, buf.String(), `"msg":"shown 1"`)
+	assert.NotContains(t, buf.String(), "dropped")
+
+	// written through the global logger at the time of the entry
+	file := filepath.Join(tempDir(t), "global.log")
+	SetOptions(WithLogFiles(file))
+
+	v.Info("replaced")
+	assert.NoError(t, Sync())
+	assert.Contains(t, readFile(t, file), `"msg":"replaced"`)
+}
@@ -0,0 +1,298 @@
+package log
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"go.uber.org/multierr"
+)
+
+var errWriterClosed = errors.New("log: write to closed writer")
+
+// rotateWriters holds the open rotating writers keyed by absolute path,
+// so that every logger writing to the same file shares one rotation state.
+var rotateWriters = struct {
+	sync.Mutex
+	m map[string]*rotateWriter
+}{
+	m: make(map[string]*rotateWriter),
+}
+
+// openRotateWriter returns a reference to the shared writer for filename,
+// creating it with config if no logger uses the file yet. A config that
+// differs from the one of the loggers before replaces it for all of them,
+// while hooks are registered per reference. The backups of filename and of
+// all files in group share the MaxTotalSize budget.
+func openRotateWriter(filename string, config RotationConfig, hooks rotateHooks, group ...string) *writerRef {
+	path := absPath(filename)
+
+	rotateWriters.Lock()
+
+	w, ok := rotateWriters.m[path]
+	if !ok {
+		w = newRotateWriter(path, config)
+		rotateWriters.m[path] = w
+
+		for _, f := range group {
+			if f := absPath(f); f != path {
+				w.group = append(w.group, f)
+			}
+		}
+	}
+
+	w.refs++
+	rotateWriters.Unlock()
+
+	// outside of the registry, as pending hooks may open other files
+	if ok {
+		w.reconfigure(config)
+	}
+
+	ref := &writerRef{rotateWriter: w}
+	w.addHooks(ref, hooks)
+
+	return ref
+}
+
+// release drops one reference and closes the writer with the last one.
+func (w *rotateWriter) release() error {
+	rotateWriters.Lock()
+	w.refs--
+	last := w.refs == 0
+
+	if last {
+		delete(rotateWriters.m, w.path)
+	}
+	rotateWriters.Unlock()
+
+	if !last {
+		return nil
+	}
+
+	return w.Close()
+}
+
+// writerRef is the handle a single logger holds on a shared rotateWriter.
+// Once released it quietly drops all writes, even if other loggers still
+// use the underlying file.
+type writerRef struct {
+	*rotateWriter
+
+	mu       sync.RWMutex
+	released bool
+}
+
+func (r *writerRef) Write(p []byte) (int, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if r.released {
+		return len(p), nil
+	}
+
+	return r.rotateWriter.Write(p)
+}
+
+func (r *writerRef) Rotate() error {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if r.released {
+		return errWriterClosed
+	}
+
+	return r.rotateWriter.Rotate()
+}
+
+func (r *writerRef) Reopen() error {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if r.released {
+		return errWriterClosed
+	}
+
+	return r.rotateWriter.Reopen()
+}
+
+// Close releases the reference. The lock is not held while the writer
+// closes, as that waits for hooks that may still log through r.
+func (r *writerRef) Close() error {
+	r.mu.Lock()
+	released := r.released
+	r.released = true
+	r.mu.Unlock()
+
+	if released {
+		return nil
+	}
+
+	r.removeHooks(r)
+
+	return r.rotateWriter.release()
+}
+
+// writerSet holds the log files of a logger.
+type writerSet struct {
+	mu     sync.Mutex
+	refs   []*writerRef
+	lazy   []*lazyWriter
+	closed bool
+}
+
+// addLazy adds a writer of LogDirs, it joins refs once it is opened.
+func (s *writerSet) addLazy(w *lazyWriter) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.lazy = append(s.lazy, w)
+}
+
+func (s *writerSet) lazyList() []*lazyWriter {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	lazy := make([]*lazyWriter, len(s.lazy))
+	copy(lazy, s.lazy)
+
+	return lazy
+}
+
+// lookupLazy returns the writer of LogDirs for the absolute path.
+func (s *writerSet) lookupLazy(path string) *lazyWriter {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, w := range s.lazy {
+		if w.path == path {
+			return w
+		}
+	}
+
+	return nil
+}
+
+// open adds a reference to the shared writer of filename, see
+// openRotateWriter. It fails once the set is closed.
+func (s *writerSet) open(filename string, config RotationConfig, hooks rotateHooks, group ...string) (*writerRef, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return nil, errWriterClosed
+	}
+
+	ref := openRotateWriter(filename, config, hooks, group...)
+	s.refs = append(s.refs, ref)
+
+	return ref, nil
+}
+
+func (s *writerSet) list() []*writerRef {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	refs := make([]*writerRef, len(s.refs))
+	copy(refs, s.refs)
+
+	return refs
+}
+
+// close releases all references. Like writerRef.Close it does not hold the
+// lock while they close, so hooks can still try to open files.
+func (s *writerSet) close() error {
+	s.mu.Lock()
+	s.closed = true
+	refs := s.refs
+	s.mu.Unlock()
+
+	var err error
+	for _, ref := range refs {
+		err = multierr.Append(err, ref.Close())
+	}
+
+	return err
+}
+
+// lazyWriter opens its writer with the first write.
+type lazyWriter struct {
+	path    string
+	enabled func() bool
+
+	mu   sync.Mutex
+	ref  *writerRef
+	open func() (*writerRef, error)
+}
+
+func (w *lazyWriter) writer() (*writerRef, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.ref == nil {
+		ref, err := w.open()
+		if err != nil {
+			return nil, err
+		}
+
+		w.ref = ref
+	}
+
+	return w.ref, nil
+}
+
+func (w *lazyWriter) Write(p []byte) (int, error) {
+	ref, err := w.writer()
+	if err == errWriterClosed {
+		// the logger was closed before the file was needed
+		return len(p), nil
+	}
+
+	if err != nil {
+		return 0, err
+	}
+
+	return ref.Write(p)
+}
+
+func (w *lazyWriter) Sync() error {
+	w.mu.Lock()
+	ref := w.ref
+	w.mu.Unlock()
+
+	if ref == nil {
+		return nil
+	}
+
+	return ref.Sync()
+}
+
+// stdWriter wraps os.Stdout and os.Stderr. Syncing a terminal or a pipe
+// fails on most platforms, which is not worth reporting to the caller.
+type stdWriter struct {
+	*os.File
+}
+
+func (w stdWriter) Sync() error {
+	_ = w.File.Sync()
+
+	return nil
+}
+
+func absPath(filename string) string {
+	path, err := filepath.Abs(filename)
+	if err != nil {
+		return filepath.Clean(filename)
+	}
+
+	return path
+}
+
+// reportError writes internal errors to stderr, like zap does for errors
+// it cannot return to the caller.
+func reportError(err error) {
+	fmt.Fprintf(os.Stderr, "%v log: %v\n", time.Now(), err)
+}
@@ -0,0 +1,93 @@
+package log
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOpenRotateWriter_Shared(t *testing.T) {
+	dir := tempDir(t)
+	file := filepath.Join(dir, "shared.log")
+
+	wd, err := os.Getwd()
+	assert.NoError(t, err)
+
+	rel, err := filepath.Rel(wd, file)
+	assert.NoError(t, err)
+
+	a := openRotateWriter(file, defaultOptions.RotationConfig, rotateHooks{})
+	b := openRotateWriter(rel, defaultOptions.RotationConfig, rotateHooks{})
+
+	assert.Same(t, a.rotateWriter, b.rotateWriter)
+	assert.Equal(t, 2, a.refs)
+
+	assert.NoError(t, a.Close())
+	assert.NoError(t, a.Close())
+	assert.Equal(t, 1, b.refs)
+
+	// dropped quietly
+	n, err := a.Write([]byte("dropped\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, 8, n)
+
+	_, err = b.Write([]byte("kept\n"))
+	assert.NoError(t, err)
+
+	assert.NoError(t, b.Close())
+	assert.True(t, b.closed)
+
+	rotateWriters.Lock()
+	_, ok := rotateWriters.m[file]
+	rotateWriters.Unlock()
+	assert.False(t, ok)
+
+	content, err := ioutil.ReadFile(file)
+	assert.NoError(t, err)
+	assert.Equal(t, "kept\n", string(content))
+}
+
+func TestLogger_WithOptionsSharesWriters(t *testing.T) {
+	dir := tempDir(t)
+	file := filepath.Join(dir, "test.log")
+
+	l := New(WithLogToStdout(false), WithLogFiles(file))
+	n := l.WithOptions(AddCaller())
+
+	assert.Same(t, l.writers.list()[0].rotateWriter, n.writers.list()[0].rotateWriter)
+
+	assert.NoError(t, l.Close())
+
+	n.Info("still open")
+	assert.NoError(t, n.Close())
+
+	content, err := ioutil.ReadFile(file)
+	assert.NoError(t, err)
+	assert.Contains(t, string(content), "still open")
+}
+
+func TestLogger_WithOptionsRotationConfig(t *testing.T) {
+	dir := tempDir(t)
+	file := filepath.Join(dir, "test.log")
+
+	l := New(WithLogToStdout(false), WithLogFiles(file))
+	n := l.WithOptions(RotationConfig{MaxBytes: 200})
+
+	assert.Equal(t, int64(200), n.writers.list()[0].maxSize)
+
+	for i := 0; i < 10; i++ {
+		n.Infow("entry", "i", i)
+	}
+
+	assert.NoError(t, l.Close())
+	assert.NoError(t, n.Close())
+
+	assert.True(t, len(readDir(t, dir)) > 1)
+
+	info, err := os.Stat(file)
+	assert.NoError(t, err)
+	assert.True(t, info.Size() <= 200)
+}